@@ -0,0 +1,194 @@
+// Package apierror defines the error codes returned by the API and writes
+// them in a single JSON envelope:
+//
+//	{"error":{"code":"invalid_tc","message":"...","details":...,"request_id":"..."}}
+//
+// Messages are Turkish by default and English when Accept-Language prefers it.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hys-go-backend/reqid"
+)
+
+// Code is a stable, machine-readable error identifier.
+type Code string
+
+const (
+	InvalidRequest   Code = "invalid_request"
+	InvalidJSON      Code = "invalid_json"
+	MissingField     Code = "missing_field"
+	InvalidTC        Code = "invalid_tc"
+	MissingTC        Code = "missing_tc"
+	InvalidCheckTime Code = "invalid_check_time"
+	InvalidGrace     Code = "invalid_grace_minutes"
+	ValidationFailed Code = "validation_failed"
+	BodyTooLarge     Code = "body_too_large"
+	UnsupportedMedia Code = "unsupported_media_type"
+	UnsupportedFile  Code = "unsupported_file_type"
+
+	LoginFailed Code = "login_failed"
+	Forbidden   Code = "forbidden"
+	NotFound    Code = "not_found"
+	Conflict    Code = "conflict"
+	RateLimited Code = "rate_limited"
+	LockedOut   Code = "too_many_failed_attempts"
+
+	StoreReadFailed  Code = "store_read_failed"
+	StoreWriteFailed Code = "store_write_failed"
+	Internal         Code = "internal_error"
+
+	ServerNotConfigured Code = "server_not_configured"
+	EnibraUpstream      Code = "enibra_upstream_error"
+	EnibraStatus        Code = "enibra_status_error"
+	EnibraHTML          Code = "enibra_error_html"
+	EnibraInvalidJSON   Code = "enibra_invalid_json"
+	EnibraEmpty         Code = "enibra_empty"
+)
+
+type entry struct {
+	status int
+	tr, en string
+}
+
+var catalog = map[Code]entry{
+	InvalidRequest:   {http.StatusBadRequest, "Geçersiz veri", "Invalid request"},
+	InvalidJSON:      {http.StatusBadRequest, "Geçersiz JSON gövdesi", "Malformed JSON body"},
+	MissingField:     {http.StatusBadRequest, "Zorunlu alan eksik", "Required field missing"},
+	InvalidTC:        {http.StatusBadRequest, "TC kimlik numarası geçersiz", "Invalid TC identity number"},
+	MissingTC:        {http.StatusBadRequest, "TC kimlik numarası gerekli", "TC identity number is required"},
+	InvalidCheckTime: {http.StatusBadRequest, "Kontrol saati okunamadı", "Invalid check_time"},
+	InvalidGrace:     {http.StatusBadRequest, "Tolerans dakikası geçersiz", "Invalid grace_min"},
+	ValidationFailed: {http.StatusUnprocessableEntity, "Bazı alanlar geçersiz", "Some fields are invalid"},
+	BodyTooLarge:     {http.StatusRequestEntityTooLarge, "İstek gövdesi çok büyük", "Request body too large"},
+	UnsupportedMedia: {http.StatusUnsupportedMediaType, "İçerik türü application/json olmalı", "Content-Type must be application/json"},
+	UnsupportedFile:  {http.StatusUnsupportedMediaType, "Dosya türü desteklenmiyor (PDF, görsel, Office, CSV)", "File type not allowed (PDF, image, Office, CSV)"},
+
+	LoginFailed: {http.StatusUnauthorized, "Bu TC ile kayıtlı personel bulunamadı", "No personnel record for this TC"},
+	Forbidden:   {http.StatusForbidden, "Bu işlem için yetkiniz yok", "You are not allowed to do this"},
+	NotFound:    {http.StatusNotFound, "Kayıt bulunamadı", "Not found"},
+	Conflict:    {http.StatusConflict, "Kayıt bu işlem için uygun durumda değil", "The record is not in a state that allows this"},
+	RateLimited: {http.StatusTooManyRequests, "Çok fazla istek, lütfen biraz bekleyin", "Too many requests, slow down"},
+	LockedOut:   {http.StatusTooManyRequests, "Çok fazla hatalı deneme, hesap geçici olarak kilitlendi", "Too many failed attempts, temporarily locked"},
+
+	StoreReadFailed:  {http.StatusInternalServerError, "Kayıtlar okunamadı", "Could not read stored data"},
+	StoreWriteFailed: {http.StatusInternalServerError, "Kayıt diske yazılamadı", "Could not persist data"},
+	Internal:         {http.StatusInternalServerError, "Beklenmeyen sunucu hatası", "Unexpected server error"},
+
+	ServerNotConfigured: {http.StatusServiceUnavailable, "Sunucu yapılandırması eksik", "Server is not configured"},
+	EnibraUpstream:      {http.StatusBadGateway, "Enibra servisine ulaşılamadı", "Enibra request failed"},
+	EnibraStatus:        {http.StatusBadGateway, "Enibra hata durumu döndü", "Enibra returned an error status"},
+	EnibraHTML:          {http.StatusBadGateway, "Enibra hata sayfası döndü", "Enibra returned an HTML error page"},
+	EnibraInvalidJSON:   {http.StatusBadGateway, "Enibra yanıtı okunamadı", "Enibra returned invalid JSON"},
+	EnibraEmpty:         {http.StatusBadGateway, "Enibra boş yanıt döndü", "Enibra returned no records"},
+}
+
+// Status returns the HTTP status associated with code (500 for unknown codes).
+func Status(code Code) int {
+	if e, ok := catalog[code]; ok {
+		return e.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Message returns the localized message for code; lang is "tr" or "en".
+func Message(code Code, lang string) string {
+	e, ok := catalog[code]
+	if !ok {
+		e = catalog[Internal]
+	}
+	if lang == "en" {
+		return e.en
+	}
+	return e.tr
+}
+
+// FieldError describes one invalid input field; a list of them goes into
+// details.fields of a validation_failed error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"` // required | invalid | too_long | unknown_field | wrong_type
+	Message string `json:"message,omitempty"`
+}
+
+type body struct {
+	Code      Code   `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Write sends the error envelope for code with the catalog status.
+// details is optional and may be nil.
+func Write(w http.ResponseWriter, r *http.Request, code Code, details any) {
+	WriteStatus(w, r, Status(code), code, details)
+}
+
+// WriteStatus is Write with an explicit HTTP status.
+func WriteStatus(w http.ResponseWriter, r *http.Request, status int, code Code, details any) {
+	lang := Language(r)
+	out := body{
+		Code:    code,
+		Message: Message(code, lang),
+		Details: details,
+	}
+	if r != nil {
+		out.RequestID = reqid.From(r.Context())
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Language", lang)
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(map[string]any{"error": out})
+}
+
+// Language picks "en" or "tr" from Accept-Language, defaulting to "tr".
+func Language(r *http.Request) string {
+	if r == nil {
+		return "tr"
+	}
+	best, bestQ := "tr", -1.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, q := parseLangPart(part)
+		if tag == "" {
+			continue
+		}
+		var lang string
+		switch {
+		case tag == "*" || strings.HasPrefix(tag, "tr"):
+			lang = "tr"
+		case strings.HasPrefix(tag, "en"):
+			lang = "en"
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+func parseLangPart(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	tag := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+	for _, f := range fields[1:] {
+		f = strings.TrimSpace(f)
+		if strings.HasPrefix(f, "q=") {
+			if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	if q <= 0 {
+		return "", 0
+	}
+	return tag, q
+}
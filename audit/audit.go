@@ -0,0 +1,231 @@
+// Package audit keeps an append-only, hash-chained record of privileged
+// actions. Each line of the file is one JSON Entry whose Hash covers the
+// entry itself and the previous entry's hash, so editing or deleting a line
+// breaks the chain from that point on and Verify reports it.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hys-go-backend/metrics"
+)
+
+// Entry is one audited action.
+type Entry struct {
+	Seq       int64           `json:"seq"`
+	Time      string          `json:"time"`
+	ActorTC   string          `json:"actor_tc,omitempty"`
+	ActorRole string          `json:"actor_role,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+// Log appends entries to a single file. It is safe for concurrent use.
+type Log struct {
+	path string
+
+	mu       sync.Mutex
+	seq      int64
+	lastHash string
+}
+
+// ErrBroken is wrapped by Verify when the chain does not match.
+var ErrBroken = errors.New("audit chain broken")
+
+// Open reads path (if it exists) to find the tail of the chain. A broken
+// chain is returned as an error together with a usable Log, so the caller
+// can report it and keep recording.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	l := &Log{path: path}
+	last, err := l.scan(nil)
+	if last != nil {
+		l.seq, l.lastHash = last.Seq, last.Hash
+	}
+	return l, err
+}
+
+// Append fills in Seq, Time (when empty) and the hashes, then writes e.
+func (l *Log) Append(e Entry) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("audit", start, err) }(time.Now())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	if e.Time == "" {
+		e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	e.PrevHash = l.lastHash
+	e.Hash, err = hashOf(e)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	l.seq, l.lastHash = e.Seq, e.Hash
+	return nil
+}
+
+// Filter narrows Query results. Zero values match everything.
+type Filter struct {
+	Action  string
+	ActorTC string
+	Target  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // en yeni kayıttan geriye; 0: sınırsız
+}
+
+func (f Filter) match(e Entry) bool {
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.ActorTC != "" && e.ActorTC != f.ActorTC {
+		return false
+	}
+	if f.Target != "" && e.Target != f.Target {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		t, err := time.Parse(time.RFC3339Nano, e.Time)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && t.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && !t.Before(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns matching entries, newest first.
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	var out []Entry
+	_, err := l.scan(func(e Entry) {
+		if f.match(e) {
+			out = append(out, e)
+		}
+	})
+	if err != nil && !errors.Is(err, ErrBroken) {
+		return nil, err
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+// Verify walks the whole file and returns the number of entries checked.
+// The error wraps ErrBroken and names the first bad sequence number.
+func (l *Log) Verify() (int64, error) {
+	var n int64
+	_, err := l.scan(func(Entry) { n++ })
+	return n, err
+}
+
+// scan reads the file under the lock, checks the chain and calls fn for
+// every entry. It returns the last entry read.
+func (l *Log) scan(fn func(Entry)) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		last   *Entry
+		broken error
+		prev   string
+		seq    int64
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			if broken == nil {
+				broken = fmt.Errorf("%w: unreadable line after seq %d", ErrBroken, seq)
+			}
+			continue
+		}
+		if broken == nil {
+			want, _ := hashOf(e)
+			if e.PrevHash != prev || e.Hash != want || e.Seq != seq+1 {
+				broken = fmt.Errorf("%w at seq %d", ErrBroken, e.Seq)
+			}
+		}
+		prev, seq = e.Hash, e.Seq
+		if fn != nil {
+			fn(e)
+		}
+		last = &e
+	}
+	if err := sc.Err(); err != nil {
+		return last, err
+	}
+	return last, broken
+}
+
+// hashOf is sha256(prev_hash || entry-without-hash).
+func hashOf(e Entry) (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
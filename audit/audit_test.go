@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newChain appends n entries (targets t1..tn) to a fresh log.
+func newChain(t *testing.T, n int) (*Log, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		if err := l.Append(Entry{ActorTC: "25031519376", Action: "allowlist.add", Target: fmt.Sprintf("t%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l, path
+}
+
+// rewriteLine replaces line n (1-based) of path with edit(line).
+func rewriteLine(t *testing.T, path string, n int, edit func(string) string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	lines[n-1] = edit(lines[n-1])
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	l, _ := newChain(t, 5)
+	n, err := l.Verify()
+	if err != nil || n != 5 {
+		t.Fatalf("Verify = %d, %v; want 5, nil", n, err)
+	}
+}
+
+func TestVerifyEmptyLog(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := l.Verify(); err != nil || n != 0 {
+		t.Fatalf("Verify = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		line int
+		edit func(string) string
+		want string
+	}{
+		{"changed target", 3, func(s string) string {
+			return strings.Replace(s, `"target":"t3"`, `"target":"tX"`, 1)
+		}, "at seq 3"},
+		{"changed actor on first line", 1, func(s string) string {
+			return strings.Replace(s, `"actor_tc":"25031519376"`, `"actor_tc":"10000000146"`, 1)
+		}, "at seq 1"},
+		{"changed hash", 2, func(s string) string {
+			// Hash alanı elle değiştirildi; sonraki kaydın prev_hash'i de tutmaz.
+			return strings.Replace(s, `"hash":"`, `"hash":"00`, 1)
+		}, "at seq 2"},
+		{"unreadable line", 4, func(string) string { return "{not json" }, "unreadable line after seq 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, path := newChain(t, 5)
+			rewriteLine(t, path, tt.line, tt.edit)
+
+			_, err := l.Verify()
+			if !errors.Is(err, ErrBroken) {
+				t.Fatalf("Verify error = %v, want ErrBroken", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify error = %q, want it to name %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsDeletedLine(t *testing.T) {
+	l, path := newChain(t, 4)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	lines = append(lines[:1], lines[2:]...) // seq 2 silindi
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Verify(); !errors.Is(err, ErrBroken) || !strings.Contains(err.Error(), "at seq 3") {
+		t.Fatalf("Verify error = %v, want break at seq 3", err)
+	}
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	_, path := newChain(t, 3)
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(Entry{Action: "auth.login", Target: "t4"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := l.Verify(); err != nil || n != 4 {
+		t.Fatalf("Verify after reopen = %d, %v; want 4, nil", n, err)
+	}
+	got, err := l.Query(Filter{Limit: 1})
+	if err != nil || len(got) != 1 || got[0].Seq != 4 {
+		t.Fatalf("newest entry = %+v, %v; want seq 4", got, err)
+	}
+}
+
+func TestOpenReportsBrokenChain(t *testing.T) {
+	_, path := newChain(t, 3)
+	rewriteLine(t, path, 2, func(s string) string { return strings.Replace(s, `"t2"`, `"tX"`, 1) })
+
+	l, err := Open(path)
+	if !errors.Is(err, ErrBroken) {
+		t.Fatalf("Open error = %v, want ErrBroken", err)
+	}
+	if l == nil {
+		t.Fatal("Open returned no Log for a broken chain")
+	}
+	// Kayıt devam eder; yeni kayıt son satırın hash'ine bağlanır.
+	if err := l.Append(Entry{Action: "auth.login"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := l.Query(Filter{Limit: 2})
+	if entries[0].PrevHash != entries[1].Hash || entries[0].Seq != 4 {
+		t.Errorf("appended entry not chained to the tail: %+v", entries[0])
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	l, _ := newChain(t, 0)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{ActorTC: "25031519376", Action: "allowlist.add", Target: "a"},
+		{ActorTC: "10000000146", Action: "allowlist.remove", Target: "a"},
+		{ActorTC: "25031519376", Action: "announcement.create", Target: "b"},
+	} {
+		e.Time = base.Add(time.Duration(i) * time.Hour).Format(time.RFC3339Nano)
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		f    Filter
+		want []int64 // seq, en yeni önce
+	}{
+		{"all", Filter{}, []int64{3, 2, 1}},
+		{"action", Filter{Action: "allowlist.add"}, []int64{1}},
+		{"actor", Filter{ActorTC: "25031519376"}, []int64{3, 1}},
+		{"target", Filter{Target: "a"}, []int64{2, 1}},
+		{"since inclusive", Filter{Since: base.Add(time.Hour)}, []int64{3, 2}},
+		{"until exclusive", Filter{Until: base.Add(time.Hour)}, []int64{1}},
+		{"limit keeps newest", Filter{Limit: 2}, []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var seqs []int64
+			for _, e := range got {
+				seqs = append(seqs, e.Seq)
+			}
+			if fmt.Sprint(seqs) != fmt.Sprint(tt.want) {
+				t.Errorf("seqs = %v, want %v", seqs, tt.want)
+			}
+		})
+	}
+}
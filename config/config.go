@@ -0,0 +1,506 @@
+// Package config loads the typed application configuration from the process
+// environment, an optional .env file and an optional YAML/TOML file.
+//
+// Precedence (highest first): environment, .env, config file, defaults.
+// Config file keys map to the same names as the environment variables, so
+// `base_url: ...` under an `enibra:` section in YAML and ENIBRA_BASE_URL are
+// equivalent.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	ListenAddr    string
+	DataDir       string
+	AllowlistFile string
+	AppVersion    string
+	TimeZone      string
+	LogLevel      string
+
+	Server ServerConfig
+	Enibra EnibraConfig
+	TLS    TLSConfig
+	CORS   CORSConfig
+	Push   PushConfig
+	Limits RateLimitConfig
+	Blob   BlobConfig
+}
+
+type ServerConfig struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+type EnibraConfig struct {
+	// PersonelListesi.doms uçları
+	BaseURL     string
+	MusteriKodu string
+	Parola      string
+	HostHeader  string
+	InsecureTLS bool
+	Timeout     time.Duration
+	CacheTTL    time.Duration
+
+	// /api/personel'in kullandığı eski JSON ucu
+	PersonelURL string
+	Key         string
+}
+
+// Configured reports whether the PersonelListesi credentials are present.
+func (e EnibraConfig) Configured() bool {
+	return e.BaseURL != "" && e.MusteriKodu != "" && e.Parola != ""
+}
+
+type TLSConfig struct {
+	Enabled        bool
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string        // doluysa /api/admin istemci sertifikası ister
+	MinVersion     string        // "1.2" | "1.3"
+	RedirectAddr   string        // doluysa burada HTTP->HTTPS yönlendirme dinlenir
+	ReloadInterval time.Duration // sertifika dosyalarını kontrol aralığı
+}
+
+// CORSConfig: izin verilen origin'ler açıkça listelenir. CORS_ALLOWED_ORIGINS
+// varsayılanı boştur (eskiden "*"): liste verilmezse hiçbir origin'e
+// cross-origin erişim izni verilmez. "*" yalnızca açıkça yazılırsa geçerlidir.
+type CORSConfig struct {
+	AllowedOrigins   []string // varsayılan grup
+	AdminOrigins     []string // /api/admin altı; boşsa AllowedOrigins
+	AllowCredentials bool
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           time.Duration
+}
+
+// RateLimitConfig: değerler dakika başına istek; 0 ilgili limiti kapatır.
+type RateLimitConfig struct {
+	Enabled           bool
+	TrustProxyHeaders bool // X-Forwarded-For / X-Real-IP'ye güven
+	LoginPerIP        int
+	LoginPerTC        int
+	EnibraPerIP       int
+	PublicPerIP       int
+	LockoutThreshold  int
+	LockoutBase       time.Duration
+	LockoutMax        time.Duration
+	PersistLockouts   bool
+}
+
+type PushConfig struct {
+	FCMServerKey string
+	APNsKeyFile  string
+	APNsKeyID    string
+	APNsTeamID   string
+	APNsTopic    string
+}
+
+// BlobConfig: duyuru eklerinin saklandığı yer. Backend "local" (DataDir/blobs)
+// ya da S3 uyumlu bir servis (MinIO vb.) olabilir.
+type BlobConfig struct {
+	Backend string // local | s3
+	Dir     string // local
+
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3VirtualHost bool // false: path-style (bucket URL yolunda)
+
+	MaxUploadBytes int64         // dosya başına
+	URLTTL         time.Duration // imzalı indirme bağlantılarının ömrü
+	URLSecret      string        // boşsa her açılışta rastgele üretilir
+}
+
+// Options says where to look for configuration files.
+type Options struct {
+	EnvFile string // usually ".env"; missing file is ignored
+	File    string // optional YAML/TOML file; missing file is an error
+}
+
+// Load reads and validates the configuration.
+func Load(opts Options) (*Config, error) {
+	src := source{file: map[string]string{}, dotenv: map[string]string{}}
+	if opts.File != "" {
+		m, err := readConfigFile(opts.File)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", opts.File, err)
+		}
+		src.file = m
+	}
+	if opts.EnvFile != "" {
+		m, err := readDotEnv(opts.EnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", opts.EnvFile, err)
+		}
+		src.dotenv = m
+	}
+
+	cfg := &Config{
+		DataDir:    src.str("DATA_DIR", "data"),
+		AppVersion: src.str("APP_VERSION", "dev"),
+		TimeZone:   src.str("TZ", ""),
+		LogLevel:   strings.ToLower(src.str("LOG_LEVEL", "info")),
+		Server: ServerConfig{
+			ReadTimeout:     src.duration("SERVER_READ_TIMEOUT", 20*time.Second),
+			WriteTimeout:    src.duration("SERVER_WRITE_TIMEOUT", 20*time.Second),
+			IdleTimeout:     src.duration("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			ShutdownTimeout: src.duration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
+		},
+		Enibra: EnibraConfig{
+			BaseURL:     strings.TrimRight(src.str("ENIBRA_BASE_URL", ""), "/"),
+			MusteriKodu: src.str("ENIBRA_MUSTERI_KODU", ""),
+			Parola:      src.str("ENIBRA_PAROLA", ""),
+			HostHeader:  src.str("ENIBRA_HOST_HEADER", ""),
+			InsecureTLS: src.boolean("ENIBRA_INSECURE_TLS"),
+			Timeout:     time.Duration(src.integer("ENIBRA_TIMEOUT_MS", 10_000)) * time.Millisecond,
+			CacheTTL:    time.Duration(src.integer("ENIBRA_CACHE_SEC", 30)) * time.Second,
+			PersonelURL: src.str("ENIBRA_URL", ""),
+		},
+		TLS: TLSConfig{
+			Enabled:        src.boolean("TLS_ENABLED"),
+			CertFile:       src.str("TLS_CERT_FILE", ""),
+			KeyFile:        src.str("TLS_KEY_FILE", ""),
+			ClientCAFile:   src.str("TLS_CLIENT_CA_FILE", ""),
+			MinVersion:     src.str("TLS_MIN_VERSION", "1.2"),
+			RedirectAddr:   src.str("TLS_REDIRECT_ADDR", ""),
+			ReloadInterval: src.duration("TLS_RELOAD_INTERVAL", 30*time.Second),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   src.list("CORS_ALLOWED_ORIGINS", nil),
+			AdminOrigins:     src.list("CORS_ADMIN_ORIGINS", nil),
+			AllowCredentials: src.boolean("CORS_ALLOW_CREDENTIALS"),
+			AllowedHeaders: src.list("CORS_ALLOWED_HEADERS", []string{
+				"Accept", "Accept-Language", "Authorization", "Content-Type",
+				"If-None-Match", "If-Modified-Since", "Last-Event-ID", "X-Requested-With", "X-Request-ID", "X-Role", "X-TC",
+			}),
+			ExposedHeaders: src.list("CORS_EXPOSED_HEADERS", []string{
+				"X-Request-ID", "ETag", "Last-Modified", "Retry-After", "Content-Disposition", "X-Next-Cursor", "Link",
+			}),
+			MaxAge: src.duration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Limits: RateLimitConfig{
+			Enabled:           !src.falsy("RATE_LIMIT_ENABLED"),
+			TrustProxyHeaders: src.boolean("TRUST_PROXY_HEADERS"),
+			LoginPerIP:        src.integer("RATE_LIMIT_LOGIN_PER_IP", 10),
+			LoginPerTC:        src.integer("RATE_LIMIT_LOGIN_PER_TC", 5),
+			EnibraPerIP:       src.integer("RATE_LIMIT_ENIBRA_PER_IP", 60),
+			PublicPerIP:       src.integer("RATE_LIMIT_PUBLIC_PER_IP", 300),
+			LockoutThreshold:  src.integer("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutBase:       src.duration("LOGIN_LOCKOUT_BASE", time.Minute),
+			LockoutMax:        src.duration("LOGIN_LOCKOUT_MAX", time.Hour),
+			PersistLockouts:   src.boolean("LOGIN_LOCKOUT_PERSIST"),
+		},
+		Push: PushConfig{
+			FCMServerKey: src.str("PUSH_FCM_SERVER_KEY", ""),
+			APNsKeyFile:  src.str("PUSH_APNS_KEY_FILE", ""),
+			APNsKeyID:    src.str("PUSH_APNS_KEY_ID", ""),
+			APNsTeamID:   src.str("PUSH_APNS_TEAM_ID", ""),
+			APNsTopic:    src.str("PUSH_APNS_TOPIC", ""),
+		},
+		Blob: BlobConfig{
+			Backend:        strings.ToLower(src.str("BLOB_BACKEND", "local")),
+			S3Endpoint:     strings.TrimRight(src.str("S3_ENDPOINT", ""), "/"),
+			S3Region:       src.str("S3_REGION", "us-east-1"),
+			S3Bucket:       src.str("S3_BUCKET", ""),
+			S3AccessKey:    src.str("S3_ACCESS_KEY", ""),
+			S3SecretKey:    src.str("S3_SECRET_KEY", ""),
+			S3VirtualHost:  src.boolean("S3_VIRTUAL_HOST"),
+			MaxUploadBytes: int64(src.integer("ATTACHMENT_MAX_MB", 10)) << 20,
+			URLTTL:         src.duration("ATTACHMENT_URL_TTL", 15*time.Minute),
+			URLSecret:      src.str("ATTACHMENT_URL_SECRET", ""),
+		},
+	}
+	cfg.Blob.Dir = src.str("BLOB_DIR", filepath.Join(cfg.DataDir, "blobs"))
+
+	cfg.AllowlistFile = src.str("ALLOWLIST_FILE", filepath.Join(cfg.DataDir, "allowlist.json"))
+
+	cfg.ListenAddr = src.str("LISTEN_ADDR", "")
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = net.JoinHostPort(src.str("HOST", "127.0.0.1"), src.str("PORT", "9090"))
+	}
+
+	if key := src.str("ENIBRA_KEY", ""); key != "" {
+		cfg.Enibra.Key = key
+	} else if enc := src.str("ENIBRA_KEY_ENC", ""); enc != "" {
+		decoded, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			src.errs = append(src.errs, fmt.Errorf("ENIBRA_KEY_ENC: not valid base64: %v", err))
+		}
+		cfg.Enibra.Key = string(decoded)
+	}
+
+	errs := append(src.errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// EnsureDirs creates the directories the configuration points at. Load
+// yalnızca doğrular; dizinler çağıran hazır olduğunda oluşturulur.
+func (c *Config) EnsureDirs() error {
+	dirs := [][2]string{{"DATA_DIR", c.DataDir}, {"ALLOWLIST_FILE", filepath.Dir(c.AllowlistFile)}}
+	if c.Blob.Backend == "local" {
+		dirs = append(dirs, [2]string{"BLOB_DIR", c.Blob.Dir})
+	}
+	var errs []error
+	for _, d := range dirs {
+		if err := os.MkdirAll(d[1], 0o755); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", d[0], err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	add := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+
+	if _, port, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		add("LISTEN_ADDR/HOST/PORT: %q is not host:port: %v", c.ListenAddr, err)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		add("PORT: %q is not a valid port", port)
+	}
+	if c.DataDir == "" {
+		add("DATA_DIR: must not be empty")
+	}
+	if c.AllowlistFile == "" {
+		add("ALLOWLIST_FILE: must not be empty")
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL: %q is not one of debug, info, warn, error", c.LogLevel)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			add("TZ: %v", err)
+		}
+	}
+	for _, d := range []struct {
+		name string
+		val  time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"ENIBRA_TIMEOUT_MS", c.Enibra.Timeout},
+		{"ENIBRA_CACHE_SEC", c.Enibra.CacheTTL},
+	} {
+		if d.val <= 0 {
+			add("%s: must be positive", d.name)
+		}
+	}
+	for _, u := range [][2]string{{"ENIBRA_BASE_URL", c.Enibra.BaseURL}, {"ENIBRA_URL", c.Enibra.PersonelURL}} {
+		if u[1] == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u[1]); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			add("%s: %q is not an absolute URL", u[0], u[1])
+		}
+	}
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			add("TLS_ENABLED: TLS_CERT_FILE and TLS_KEY_FILE are required")
+		}
+		switch c.TLS.MinVersion {
+		case "1.2", "1.3":
+		default:
+			add("TLS_MIN_VERSION: %q must be 1.2 or 1.3", c.TLS.MinVersion)
+		}
+		if c.TLS.ReloadInterval <= 0 {
+			add("TLS_RELOAD_INTERVAL: must be positive")
+		}
+		if c.TLS.RedirectAddr != "" {
+			if _, _, err := net.SplitHostPort(c.TLS.RedirectAddr); err != nil {
+				add("TLS_REDIRECT_ADDR: %v", err)
+			}
+		}
+		for _, f := range []string{c.TLS.CertFile, c.TLS.KeyFile, c.TLS.ClientCAFile} {
+			if f == "" {
+				continue
+			}
+			if _, err := os.Stat(f); err != nil {
+				add("TLS: %v", err)
+			}
+		}
+	}
+	for _, group := range []struct {
+		key     string
+		origins []string
+	}{{"CORS_ALLOWED_ORIGINS", c.CORS.AllowedOrigins}, {"CORS_ADMIN_ORIGINS", c.CORS.AdminOrigins}} {
+		for _, o := range group.origins {
+			if o == "*" {
+				if c.CORS.AllowCredentials {
+					add("%s: \"*\" cannot be combined with CORS_ALLOW_CREDENTIALS", group.key)
+				}
+				continue
+			}
+			if u, err := url.Parse(o); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+				add("%s: %q must be \"*\" or scheme://host[:port]", group.key, o)
+			}
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		add("CORS_MAX_AGE: must not be negative")
+	}
+	for _, n := range []struct {
+		name string
+		val  int
+	}{
+		{"RATE_LIMIT_LOGIN_PER_IP", c.Limits.LoginPerIP},
+		{"RATE_LIMIT_LOGIN_PER_TC", c.Limits.LoginPerTC},
+		{"RATE_LIMIT_ENIBRA_PER_IP", c.Limits.EnibraPerIP},
+		{"RATE_LIMIT_PUBLIC_PER_IP", c.Limits.PublicPerIP},
+		{"LOGIN_LOCKOUT_THRESHOLD", c.Limits.LockoutThreshold},
+	} {
+		if n.val < 0 {
+			add("%s: must not be negative", n.name)
+		}
+	}
+	if c.Limits.LockoutBase <= 0 || c.Limits.LockoutMax < c.Limits.LockoutBase {
+		add("LOGIN_LOCKOUT_BASE/MAX: need 0 < base <= max")
+	}
+	switch c.Blob.Backend {
+	case "local":
+		if c.Blob.Dir == "" {
+			add("BLOB_DIR: must not be empty")
+		}
+	case "s3":
+		if parsed, err := url.Parse(c.Blob.S3Endpoint); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			add("S3_ENDPOINT: %q is not an absolute URL", c.Blob.S3Endpoint)
+		}
+		if c.Blob.S3Bucket == "" || c.Blob.S3AccessKey == "" || c.Blob.S3SecretKey == "" {
+			add("BLOB_BACKEND=s3: S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required")
+		}
+	default:
+		add("BLOB_BACKEND: %q must be local or s3", c.Blob.Backend)
+	}
+	if c.Blob.MaxUploadBytes <= 0 {
+		add("ATTACHMENT_MAX_MB: must be positive")
+	}
+	if c.Blob.URLTTL <= 0 {
+		add("ATTACHMENT_URL_TTL: must be positive")
+	}
+	if c.Push.APNsKeyFile != "" {
+		if _, err := os.Stat(c.Push.APNsKeyFile); err != nil {
+			add("PUSH_APNS_KEY_FILE: %v", err)
+		}
+	}
+	return errs
+}
+
+// ===================== value source =====================
+
+type source struct {
+	file   map[string]string
+	dotenv map[string]string
+	errs   []error
+}
+
+func (s *source) lookup(key string) (string, bool) {
+	if v, ok := os.LookupEnv(key); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v), true
+	}
+	if v, ok := s.dotenv[key]; ok && v != "" {
+		return v, true
+	}
+	if v, ok := s.file[key]; ok && v != "" {
+		return v, true
+	}
+	return "", false
+}
+
+func (s *source) str(key, def string) string {
+	if v, ok := s.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func (s *source) integer(key string, def int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not an integer", key, v))
+		return def
+	}
+	return n
+}
+
+func (s *source) boolean(key string) bool {
+	v, ok := s.lookup(key)
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	s.errs = append(s.errs, fmt.Errorf("%s: %q is not a boolean", key, v))
+	return false
+}
+
+// falsy reports whether key is explicitly set to a false value; used for
+// switches that default to on.
+func (s *source) falsy(key string) bool {
+	if _, ok := s.lookup(key); !ok {
+		return false
+	}
+	return !s.boolean(key)
+}
+
+// duration accepts Go durations ("20s", "1m") or plain seconds ("20").
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %q is not a duration", key, v))
+		return def
+	}
+	return d
+}
+
+func (s *source) list(key string, def []string) []string {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
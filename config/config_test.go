@@ -0,0 +1,194 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configKeys is every variable Load reads; cleared so the test process
+// environment cannot leak into the results.
+var configKeys = strings.Fields(`ALLOWLIST_FILE APP_VERSION ATTACHMENT_MAX_MB ATTACHMENT_URL_SECRET
+	ATTACHMENT_URL_TTL BLOB_BACKEND BLOB_DIR CORS_ADMIN_ORIGINS CORS_ALLOWED_HEADERS CORS_ALLOWED_ORIGINS
+	CORS_ALLOW_CREDENTIALS CORS_EXPOSED_HEADERS CORS_MAX_AGE DATA_DIR ENIBRA_BASE_URL ENIBRA_CACHE_SEC
+	ENIBRA_HOST_HEADER ENIBRA_INSECURE_TLS ENIBRA_KEY ENIBRA_KEY_ENC ENIBRA_MUSTERI_KODU ENIBRA_PAROLA
+	ENIBRA_TIMEOUT_MS ENIBRA_URL HOST LISTEN_ADDR LOGIN_LOCKOUT_BASE LOGIN_LOCKOUT_MAX LOGIN_LOCKOUT_PERSIST
+	LOGIN_LOCKOUT_THRESHOLD LOG_LEVEL PORT PUSH_APNS_KEY_FILE PUSH_APNS_KEY_ID PUSH_APNS_TEAM_ID
+	PUSH_APNS_TOPIC PUSH_FCM_SERVER_KEY RATE_LIMIT_ENABLED RATE_LIMIT_ENIBRA_PER_IP RATE_LIMIT_LOGIN_PER_IP
+	RATE_LIMIT_LOGIN_PER_TC RATE_LIMIT_PUBLIC_PER_IP S3_ACCESS_KEY S3_BUCKET S3_ENDPOINT S3_REGION
+	S3_SECRET_KEY S3_VIRTUAL_HOST SERVER_IDLE_TIMEOUT SERVER_READ_TIMEOUT SERVER_SHUTDOWN_TIMEOUT
+	SERVER_WRITE_TIMEOUT TLS_CERT_FILE TLS_CLIENT_CA_FILE TLS_ENABLED TLS_KEY_FILE TLS_MIN_VERSION
+	TLS_REDIRECT_ADDR TLS_RELOAD_INTERVAL TRUST_PROXY_HEADERS TZ`)
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range configKeys {
+		t.Setenv(k, "") // boş değer tanımsız sayılır
+	}
+}
+
+// writeSources writes the optional YAML file and .env into a temp dir.
+func writeSources(t *testing.T, yaml, dotenv string) Options {
+	t.Helper()
+	dir := t.TempDir()
+	opts := Options{EnvFile: filepath.Join(dir, ".env")}
+	if yaml != "" {
+		opts.File = filepath.Join(dir, "config.yaml")
+		if err := os.WriteFile(opts.File, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dotenv != "" {
+		if err := os.WriteFile(opts.EnvFile, []byte(dotenv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return opts
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load(writeSources(t, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"ListenAddr", cfg.ListenAddr, "127.0.0.1:9090"},
+		{"DataDir", cfg.DataDir, "data"},
+		{"AllowlistFile", cfg.AllowlistFile, filepath.Join("data", "allowlist.json")},
+		{"Blob.Dir", cfg.Blob.Dir, filepath.Join("data", "blobs")},
+		{"LogLevel", cfg.LogLevel, "info"},
+		{"Enibra.Timeout", cfg.Enibra.Timeout, 10 * time.Second},
+		{"Limits.Enabled", cfg.Limits.Enabled, true},
+		{"Limits.LockoutThreshold", cfg.Limits.LockoutThreshold, 5},
+		{"CORS.AllowedOrigins", len(cfg.CORS.AllowedOrigins), 0},
+		{"Blob.MaxUploadBytes", cfg.Blob.MaxUploadBytes, int64(10 << 20)},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const (
+		yaml   = "port: 1001\nlog_level: warn\nenibra:\n  timeout_ms: 3000\n  cache_sec: 7\n"
+		dotenv = "PORT=1002\nLOG_LEVEL=error\nENIBRA_TIMEOUT_MS=4000\n"
+	)
+	tests := []struct {
+		name    string
+		yaml    string
+		dotenv  string
+		env     map[string]string
+		port    string
+		level   string
+		timeout time.Duration
+		cache   time.Duration
+	}{
+		{"defaults only", "", "", nil, "9090", "info", 10 * time.Second, 30 * time.Second},
+		{"file over defaults", yaml, "", nil, "1001", "warn", 3 * time.Second, 7 * time.Second},
+		{".env over file", yaml, dotenv, nil, "1002", "error", 4 * time.Second, 7 * time.Second},
+		{"env over .env", yaml, dotenv, map[string]string{"PORT": "1003", "ENIBRA_CACHE_SEC": "9"}, "1003", "error", 4 * time.Second, 9 * time.Second},
+		{"blank env falls through", yaml, dotenv, map[string]string{"PORT": "  "}, "1002", "error", 4 * time.Second, 7 * time.Second},
+		{"empty .env value falls through", yaml, "PORT=\n", nil, "1001", "warn", 3 * time.Second, 7 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(writeSources(t, tt.yaml, tt.dotenv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ListenAddr != "127.0.0.1:"+tt.port {
+				t.Errorf("ListenAddr = %s, want port %s", cfg.ListenAddr, tt.port)
+			}
+			if cfg.LogLevel != tt.level {
+				t.Errorf("LogLevel = %s, want %s", cfg.LogLevel, tt.level)
+			}
+			if cfg.Enibra.Timeout != tt.timeout || cfg.Enibra.CacheTTL != tt.cache {
+				t.Errorf("Enibra timeout/cache = %v/%v, want %v/%v", cfg.Enibra.Timeout, cfg.Enibra.CacheTTL, tt.timeout, tt.cache)
+			}
+		})
+	}
+}
+
+func TestLoadDerivedPaths(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATA_DIR", "/srv/hys")
+	t.Setenv("LISTEN_ADDR", "0.0.0.0:8443")
+	t.Setenv("PORT", "1") // LISTEN_ADDR varken yok sayılır
+	cfg, err := Load(writeSources(t, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AllowlistFile != "/srv/hys/allowlist.json" || cfg.Blob.Dir != "/srv/hys/blobs" {
+		t.Errorf("paths = %s, %s", cfg.AllowlistFile, cfg.Blob.Dir)
+	}
+	if cfg.ListenAddr != "0.0.0.0:8443" {
+		t.Errorf("ListenAddr = %s", cfg.ListenAddr)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"port out of range", map[string]string{"PORT": "70000"}, []string{`PORT: "70000" is not a valid port`}},
+		{"bad listen addr", map[string]string{"LISTEN_ADDR": "localhost"}, []string{"LISTEN_ADDR/HOST/PORT"}},
+		{"log level", map[string]string{"LOG_LEVEL": "verbose"}, []string{`LOG_LEVEL: "verbose"`}},
+		{"time zone", map[string]string{"TZ": "Mars/Olympus"}, []string{"TZ:"}},
+		{"non-integer", map[string]string{"RATE_LIMIT_LOGIN_PER_IP": "ten"}, []string{`RATE_LIMIT_LOGIN_PER_IP: "ten" is not an integer`}},
+		{"negative limit", map[string]string{"RATE_LIMIT_PUBLIC_PER_IP": "-1"}, []string{"RATE_LIMIT_PUBLIC_PER_IP: must not be negative"}},
+		{"non-boolean", map[string]string{"RATE_LIMIT_ENABLED": "maybe"}, []string{`RATE_LIMIT_ENABLED: "maybe" is not a boolean`}},
+		{"bad duration", map[string]string{"SERVER_READ_TIMEOUT": "soon"}, []string{`SERVER_READ_TIMEOUT: "soon" is not a duration`}},
+		{"zero duration", map[string]string{"SERVER_WRITE_TIMEOUT": "0"}, []string{"SERVER_WRITE_TIMEOUT: must be positive"}},
+		{"relative enibra url", map[string]string{"ENIBRA_BASE_URL": "enibra.local"}, []string{"ENIBRA_BASE_URL"}},
+		{"bad key encoding", map[string]string{"ENIBRA_KEY_ENC": "%%%"}, []string{"ENIBRA_KEY_ENC: not valid base64"}},
+		{"tls without files", map[string]string{"TLS_ENABLED": "true"}, []string{"TLS_CERT_FILE and TLS_KEY_FILE are required"}},
+		{"cors wildcard with credentials", map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, []string{"cannot be combined with CORS_ALLOW_CREDENTIALS"}},
+		{"cors origin with path", map[string]string{"CORS_ADMIN_ORIGINS": "https://panel.example.com/admin"}, []string{"CORS_ADMIN_ORIGINS"}},
+		{"lockout base above max", map[string]string{"LOGIN_LOCKOUT_BASE": "2h", "LOGIN_LOCKOUT_MAX": "1h"}, []string{"LOGIN_LOCKOUT_BASE/MAX"}},
+		{"blob backend", map[string]string{"BLOB_BACKEND": "ftp"}, []string{`BLOB_BACKEND: "ftp"`}},
+		{"s3 incomplete", map[string]string{"BLOB_BACKEND": "s3", "S3_ENDPOINT": "https://s3.example.com"}, []string{"S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required"}},
+		{"every error reported", map[string]string{"PORT": "0", "LOG_LEVEL": "x", "BLOB_BACKEND": "ftp"}, []string{"PORT:", "LOG_LEVEL:", "BLOB_BACKEND:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(writeSources(t, "", ""))
+			if err == nil {
+				t.Fatalf("Load succeeded: %+v", cfg)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not mention %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	clearEnv(t)
+	if _, err := Load(Options{File: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("missing config file accepted")
+	}
+	opts := writeSources(t, "enibra:\n  auth:\n    key: x\n", "")
+	if _, err := Load(opts); err == nil || !strings.Contains(err.Error(), "only one level") {
+		t.Errorf("nested YAML: error = %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readDotEnv parses KEY=VALUE lines. A missing file is not an error.
+func readDotEnv(path string) (map[string]string, error) {
+	out := map[string]string{}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		if idx := strings.Index(line, "="); idx >= 0 {
+			key := strings.TrimSpace(line[:idx])
+			if key == "" {
+				continue
+			}
+			out[key] = unquote(strings.TrimSpace(line[idx+1:]))
+		}
+	}
+	return out, scanner.Err()
+}
+
+// readConfigFile reads a YAML or TOML file (chosen by extension) and returns
+// its values keyed by the matching environment variable name. Only the subset
+// we need is supported: scalar values, one level of sections, and inline lists.
+//
+//	# YAML                       # TOML
+//	port: 9090                   port = 9090
+//	enibra:                      [enibra]
+//	  base_url: https://...      base_url = "https://..."
+//
+// Both produce PORT and ENIBRA_BASE_URL.
+func readConfigFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return parseYAML(string(b))
+	case ".toml":
+		return parseTOML(string(b))
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (want .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+}
+
+// parseYAML accepts top-level scalars and one level of sections. Every key in
+// a section must use the section's indent width; a deeper line (a nested map
+// or a continuation) is rejected instead of being flattened into the section.
+func parseYAML(src string) (map[string]string, error) {
+	out := map[string]string{}
+	section := ""
+	indent := 0 // bölümdeki ilk anahtarın girinti genişliği
+	for n, raw := range strings.Split(src, "\n") {
+		line := stripComment(strings.TrimRight(raw, " \t\r"))
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		body := strings.TrimLeft(line, " \t")
+		width := len(line) - len(body)
+		if strings.Contains(line[:width], "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n+1)
+		}
+		key, val, ok := strings.Cut(body, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if strings.HasPrefix(val, "{") {
+			return nil, fmt.Errorf("line %d: inline maps are not supported, use an indented section", n+1)
+		}
+		if width == 0 {
+			section, indent = "", 0
+			if val == "" {
+				section = key
+				continue
+			}
+			out[envKey("", key)] = scalar(val)
+			continue
+		}
+		switch {
+		case section == "":
+			return nil, fmt.Errorf("line %d: nested value without a section", n+1)
+		case indent == 0:
+			indent = width
+		case width > indent:
+			return nil, fmt.Errorf("line %d: only one level of sections is supported", n+1)
+		case width < indent:
+			return nil, fmt.Errorf("line %d: indentation does not match the rest of section %q", n+1, section)
+		}
+		if val == "" {
+			return nil, fmt.Errorf("line %d: only one level of sections is supported", n+1)
+		}
+		out[envKey(section, key)] = scalar(val)
+	}
+	return out, nil
+}
+
+func parseTOML(src string) (map[string]string, error) {
+	out := map[string]string{}
+	section := ""
+	for n, raw := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		out[envKey(section, strings.TrimSpace(key))] = scalar(strings.TrimSpace(val))
+	}
+	return out, nil
+}
+
+func envKey(section, key string) string {
+	k := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+	if section == "" {
+		return k
+	}
+	return strings.ToUpper(strings.ReplaceAll(section, "-", "_")) + "_" + k
+}
+
+// scalar turns `"a"`, `'a'` and `["a", "b"]` into env-style strings.
+func scalar(v string) string {
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		parts := strings.Split(v[1:len(v)-1], ",")
+		items := make([]string, 0, len(parts))
+		for _, p := range parts {
+			if p = unquote(strings.TrimSpace(p)); p != "" {
+				items = append(items, p)
+			}
+		}
+		return strings.Join(items, ",")
+	}
+	return unquote(v)
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' && v[len(v)-1] == '"' || v[0] == '\'' && v[len(v)-1] == '\'') {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// stripComment drops a trailing "# ..." that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
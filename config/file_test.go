@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "scalars and one section",
+			src: `# yorum
+port: 9090
+app_version: "1.2.3"   # satır sonu yorumu
+---
+enibra:
+  base_url: https://enibra.example.com
+  musteri-kodu: 'ABC#1'
+cors:
+  allowed_origins: ["https://a.example.com", 'https://b.example.com']
+log_level: debug
+`,
+			want: map[string]string{
+				"PORT":                 "9090",
+				"APP_VERSION":          "1.2.3",
+				"ENIBRA_BASE_URL":      "https://enibra.example.com",
+				"ENIBRA_MUSTERI_KODU":  "ABC#1",
+				"CORS_ALLOWED_ORIGINS": "https://a.example.com,https://b.example.com",
+				"LOG_LEVEL":            "debug",
+			},
+		},
+		{
+			name: "four-space sections",
+			src:  "tls:\n    enabled: true\n    cert_file: /etc/cert.pem\n",
+			want: map[string]string{"TLS_ENABLED": "true", "TLS_CERT_FILE": "/etc/cert.pem"},
+		},
+		{
+			name: "each section keeps its own width",
+			src:  "a:\n  x: 1\nb:\n    y: 2\n",
+			want: map[string]string{"A_X": "1", "B_Y": "2"},
+		},
+		{
+			name: "blank lines and comments inside a section",
+			src:  "server:\n\n  # yorum\n  read_timeout: 20s\n\n  idle_timeout: 60s\n",
+			want: map[string]string{"SERVER_READ_TIMEOUT": "20s", "SERVER_IDLE_TIMEOUT": "60s"},
+		},
+		{name: "third level", src: "enibra:\n  auth:\n    key: x\n", wantErr: "line 2: only one level"},
+		{name: "deeper line after a key", src: "enibra:\n  base_url: a\n    key: x\n", wantErr: "line 3: only one level"},
+		{name: "shallower line in section", src: "enibra:\n    base_url: a\n  key: x\n", wantErr: "line 3: indentation does not match"},
+		{name: "tab indent", src: "enibra:\n\tbase_url: a\n", wantErr: "line 2: tabs are not allowed"},
+		{name: "indented without section", src: "port: 1\n  host: x\n", wantErr: "line 2: nested value without a section"},
+		{name: "inline map", src: "enibra: {base_url: a}\n", wantErr: "line 1: inline maps"},
+		{name: "no colon", src: "port 9090\n", wantErr: "line 1: expected key: value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "top level and sections",
+			src: `port = 9090
+app_version = "1.2.3" # yorum
+
+[enibra]
+base_url = "https://enibra.example.com"
+parola = 'p#ss'
+
+[rate-limit]
+login_per_ip = 10
+`,
+			want: map[string]string{
+				"PORT":                    "9090",
+				"APP_VERSION":             "1.2.3",
+				"ENIBRA_BASE_URL":         "https://enibra.example.com",
+				"ENIBRA_PAROLA":           "p#ss",
+				"RATE_LIMIT_LOGIN_PER_IP": "10",
+			},
+		},
+		{
+			name: "inline list",
+			src:  "[cors]\nallowed_origins = [\"https://a.example.com\", \"https://b.example.com\",]\n",
+			want: map[string]string{"CORS_ALLOWED_ORIGINS": "https://a.example.com,https://b.example.com"},
+		},
+		{name: "missing equals", src: "[enibra]\nbase_url\n", wantErr: "line 2: expected key = value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	src := "# yorum\nexport PORT=9091\nENIBRA_PAROLA=\"a b\"\nEMPTY=\n=novalue\nNOEQUALS\n"
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readDotEnv(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"PORT": "9091", "ENIBRA_PAROLA": "a b", "EMPTY": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, err = readDotEnv(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("missing file: %v, %v; want empty, nil", got, err)
+	}
+}
+
+func TestReadConfigFileExtension(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{"c.yml": "port: 1\n", "c.YAML": "port: 1\n", "c.toml": "port = 1\n"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if m, err := readConfigFile(path); err != nil || m["PORT"] != "1" {
+			t.Errorf("%s: %v, %v", name, m, err)
+		}
+	}
+	path := filepath.Join(dir, "c.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfigFile(path); err == nil || !strings.Contains(err.Error(), "unsupported config file extension") {
+		t.Errorf("json: error = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import "strings"
+
+// Merge returns a copy of current with the settings that can change at
+// runtime taken from next: Enibra, CORS, log level and the allowlist file.
+// Everything else (blob storage included) needs a restart; those keys are
+// returned in ignored.
+func Merge(current, next *Config) (merged *Config, ignored []string) {
+	out := *current
+	out.Enibra = next.Enibra
+	out.CORS = next.CORS
+	out.LogLevel = next.LogLevel
+	out.AllowlistFile = next.AllowlistFile
+
+	// Kalan farklar (adres, TLS, timeout...) yeniden başlatma ister.
+	candidate := *next
+	candidate.Enibra = current.Enibra
+	candidate.CORS = current.CORS
+	candidate.LogLevel = current.LogLevel
+	candidate.AllowlistFile = current.AllowlistFile
+	for _, d := range Diff(current, &candidate) {
+		key, _, _ := strings.Cut(d, ":")
+		ignored = append(ignored, key)
+	}
+	return &out, ignored
+}
+
+// Diff lists "key: old -> new" for every report line that differs.
+// Secrets are compared in masked form, so a changed password shows as
+// "enibra.parola: **** -> ****".
+func Diff(old, next *Config) []string {
+	a, b := reportMap(old), reportMap(next)
+	var out []string
+	for _, line := range next.Report() {
+		key, _, _ := strings.Cut(line, " = ")
+		if a[key].value != b[key].value || a[key].raw != b[key].raw {
+			out = append(out, key+": "+a[key].value+" -> "+b[key].value)
+		}
+	}
+	return out
+}
+
+type reportEntry struct {
+	value string // as printed (redacted)
+	raw   string // unredacted, only used for comparison
+}
+
+func reportMap(c *Config) map[string]reportEntry {
+	m := map[string]reportEntry{}
+	raw := c.rawSecrets()
+	for _, line := range c.Report() {
+		key, val, _ := strings.Cut(line, " = ")
+		m[key] = reportEntry{value: val, raw: raw[key]}
+	}
+	return m
+}
+
+func (c *Config) rawSecrets() map[string]string {
+	return map[string]string{
+		"enibra.parola":       c.Enibra.Parola,
+		"enibra.url":          c.Enibra.PersonelURL,
+		"enibra.key":          c.Enibra.Key,
+		"push.fcm_server_key": c.Push.FCMServerKey,
+		"blob.s3_secret_key":  c.Blob.S3SecretKey,
+		"blob.url_secret":     c.Blob.URLSecret,
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Report returns the effective configuration as "key = value" lines with
+// secrets masked, suitable for logging at startup.
+func (c *Config) Report() []string {
+	lines := []string{
+		kv("listen_addr", c.ListenAddr),
+		kv("data_dir", c.DataDir),
+		kv("allowlist_file", c.AllowlistFile),
+		kv("app_version", c.AppVersion),
+		kv("tz", c.TimeZone),
+		kv("log_level", c.LogLevel),
+		kv("server.read_timeout", c.Server.ReadTimeout),
+		kv("server.write_timeout", c.Server.WriteTimeout),
+		kv("server.idle_timeout", c.Server.IdleTimeout),
+		kv("server.shutdown_timeout", c.Server.ShutdownTimeout),
+		kv("enibra.base_url", c.Enibra.BaseURL),
+		kv("enibra.musteri_kodu", c.Enibra.MusteriKodu),
+		kv("enibra.parola", redact(c.Enibra.Parola)),
+		kv("enibra.host_header", c.Enibra.HostHeader),
+		kv("enibra.insecure_tls", c.Enibra.InsecureTLS),
+		kv("enibra.timeout", c.Enibra.Timeout),
+		kv("enibra.cache_ttl", c.Enibra.CacheTTL),
+		kv("enibra.url", redactURL(c.Enibra.PersonelURL)),
+		kv("enibra.key", redact(c.Enibra.Key)),
+		kv("tls.enabled", c.TLS.Enabled),
+		kv("tls.cert_file", c.TLS.CertFile),
+		kv("tls.key_file", c.TLS.KeyFile),
+		kv("tls.client_ca_file", c.TLS.ClientCAFile),
+		kv("tls.min_version", c.TLS.MinVersion),
+		kv("tls.redirect_addr", c.TLS.RedirectAddr),
+		kv("tls.reload_interval", c.TLS.ReloadInterval),
+		kv("cors.allowed_origins", strings.Join(c.CORS.AllowedOrigins, ",")),
+		kv("cors.admin_origins", strings.Join(c.CORS.AdminOrigins, ",")),
+		kv("cors.allow_credentials", c.CORS.AllowCredentials),
+		kv("cors.allowed_headers", strings.Join(c.CORS.AllowedHeaders, ",")),
+		kv("cors.exposed_headers", strings.Join(c.CORS.ExposedHeaders, ",")),
+		kv("cors.max_age", c.CORS.MaxAge),
+		kv("limits.enabled", c.Limits.Enabled),
+		kv("limits.trust_proxy_headers", c.Limits.TrustProxyHeaders),
+		kv("limits.login_per_ip", c.Limits.LoginPerIP),
+		kv("limits.login_per_tc", c.Limits.LoginPerTC),
+		kv("limits.enibra_per_ip", c.Limits.EnibraPerIP),
+		kv("limits.public_per_ip", c.Limits.PublicPerIP),
+		kv("limits.lockout_threshold", c.Limits.LockoutThreshold),
+		kv("limits.lockout_base", c.Limits.LockoutBase),
+		kv("limits.lockout_max", c.Limits.LockoutMax),
+		kv("limits.persist_lockouts", c.Limits.PersistLockouts),
+		kv("push.fcm_server_key", redact(c.Push.FCMServerKey)),
+		kv("push.apns_key_file", c.Push.APNsKeyFile),
+		kv("push.apns_key_id", c.Push.APNsKeyID),
+		kv("push.apns_team_id", c.Push.APNsTeamID),
+		kv("push.apns_topic", c.Push.APNsTopic),
+		kv("blob.backend", c.Blob.Backend),
+		kv("blob.dir", c.Blob.Dir),
+		kv("blob.s3_endpoint", c.Blob.S3Endpoint),
+		kv("blob.s3_region", c.Blob.S3Region),
+		kv("blob.s3_bucket", c.Blob.S3Bucket),
+		kv("blob.s3_access_key", c.Blob.S3AccessKey),
+		kv("blob.s3_secret_key", redact(c.Blob.S3SecretKey)),
+		kv("blob.s3_virtual_host", c.Blob.S3VirtualHost),
+		kv("blob.max_upload_bytes", c.Blob.MaxUploadBytes),
+		kv("blob.url_ttl", c.Blob.URLTTL),
+		kv("blob.url_secret", redact(c.Blob.URLSecret)),
+	}
+	return lines
+}
+
+func kv(k string, v any) string {
+	s := fmt.Sprint(v)
+	if s == "" {
+		s = "(unset)"
+	}
+	return k + " = " + s
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "****"
+}
+
+// redactURL masks query values such as ?key=... that may carry credentials.
+func redactURL(raw string) string {
+	if i := strings.Index(raw, "?"); i >= 0 {
+		return raw[:i] + "?****"
+	}
+	return raw
+}
@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
 	"log"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sync"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
+	"hys-go-backend/rbac"
 
 	"github.com/gorilla/mux"
 )
@@ -17,6 +22,19 @@ type allowItem struct {
 	TC   string `json:"tc"`
 	Role string `json:"role"`
 	Name string `json:"name,omitempty"`
+
+	// Veri erişim kapsamı için elle girilen değerler (bkz. scope.go).
+	// Boşsa kapsam rol yetkilerinden, şube Enibra kaydından çıkarılır.
+	Scope  string   `json:"scope,omitempty"`  // own | branch | region | company
+	Branch string   `json:"branch,omitempty"` // Enibra SUBE yerine geçer
+	Region []string `json:"region,omitempty"` // region kapsamındaki şubeler
+
+	// Süreli ve devredilmiş yetkiler (bkz. allowlist_expiry.go, delegation.go).
+	ValidFrom     *time.Time `json:"valid_from,omitempty"`
+	ValidUntil    *time.Time `json:"valid_until,omitempty"`
+	GrantedBy     string     `json:"granted_by,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	DelegatedFrom string     `json:"delegated_from,omitempty"`
 }
 
 type allowStore struct {
@@ -24,26 +42,21 @@ type allowStore struct {
 	ByTC map[string]allowItem
 }
 
-var (
-	allowDB         = &allowStore{ByTC: map[string]allowItem{}}
-	allowFile       = filepath.Join("data", "allowlist.json")
-	tcRegex         = regexp.MustCompile(`^\d{11}$`)
-	defaultRole     = "admin"
-	onceLoadAllowDB sync.Once
-)
+var defaultRole = rbac.Admin
 
 // --- Public Handlers ---
 
 // GET /api/admin/allowlist
-func GetAllowlist(w http.ResponseWriter, r *http.Request) {
-	ensureLoaded()
+func (api *API) GetAllowlist(w http.ResponseWriter, r *http.Request) {
+	api.ensureLoaded()
 
-	allowDB.RLock()
-	defer allowDB.RUnlock()
+	api.allowDB.RLock()
+	defer api.allowDB.RUnlock()
 
-	var list []allowItem
-	for _, v := range allowDB.ByTC {
-		list = append(list, v)
+	now := time.Now()
+	var list []allowView
+	for _, v := range api.allowDB.ByTC {
+		list = append(list, allowView{allowItem: v, Status: v.status(now)})
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
@@ -53,31 +66,70 @@ func GetAllowlist(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST /api/admin/allowlist   body: {"tc":"25031519376","role":"admin","name":"Yusuf Ege"}
-func AddAllowlist(w http.ResponseWriter, r *http.Request) {
-	ensureLoaded()
+// Opsiyonel: "valid_from", "valid_until" (RFC3339), "reason". Çağıran yalnızca
+// kendi yetkilerinin alt kümesi olan bir rol verebilir (bkz. grantable).
+func (api *API) AddAllowlist(w http.ResponseWriter, r *http.Request) {
+	api.ensureLoaded()
 
 	var in allowItem
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "gecersiz json", http.StatusBadRequest)
+	if !decodeJSON(w, r, &in) {
 		return
 	}
-	in.TC = normalizeTC(in.TC)
+	in.TC = identity.Normalize(in.TC)
 	if in.Role == "" {
-		in.Role = defaultRole
+		in.Role = string(defaultRole)
 	}
-
-	if err := validateTC(in.TC); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	role, ok := rbac.ParseRole(in.Role)
+	if !ok {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: "role", Code: "invalid", Message: roleChoices(),
+		}}})
 		return
 	}
+	if !grantable(api.Policy(), requestRole(r), role) {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"role": role})
+		return
+	}
+	in.Role = string(role)
+	if in.Scope != "" {
+		if _, ok := parseScopeLevel(in.Scope); !ok {
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+				Field: "scope", Code: "invalid", Message: "own | branch | region | company",
+			}}})
+			return
+		}
+	}
 
-	allowDB.Lock()
-	allowDB.ByTC[in.TC] = in
-	allowDB.Unlock()
-
-	if err := persist(); err != nil {
+	if err := identity.Validate(in.TC); err != nil {
+		apierror.Write(w, r, apierror.InvalidTC, map[string]any{"reason": err.Error()})
+		return
+	}
+	if fields := validateWindow(in.ValidFrom, in.ValidUntil, time.Now()); len(fields) > 0 {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": fields})
+		return
+	}
+	in.GrantedBy = actorTC(r)
+	in.DelegatedFrom = ""
+
+	api.allowDB.Lock()
+	prev, existed := api.allowDB.ByTC[in.TC]
+	next := maps.Clone(api.allowDB.ByTC)
+	next[in.TC] = in
+	if err := api.persistLocked(next); err != nil {
+		api.allowDB.Unlock()
 		log.Println("allowlist kaydedilemedi:", err)
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	api.allowDB.ByTC = next
+	api.allowDB.Unlock()
+
+	log.Printf("[INFO] allowlist: %s eklendi (role=%s)", identity.Mask(in.TC), in.Role)
+	var before any
+	if existed {
+		before = prev
 	}
+	api.recordAudit(r, "allowlist.add", in.TC, before, in)
 
 	writeJSON(w, http.StatusCreated, map[string]any{
 		"ok":   true,
@@ -86,22 +138,27 @@ func AddAllowlist(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE /api/admin/allowlist/{tc}
-func RemoveAllowlist(w http.ResponseWriter, r *http.Request) {
-	ensureLoaded()
+func (api *API) RemoveAllowlist(w http.ResponseWriter, r *http.Request) {
+	api.ensureLoaded()
 
-	tc := normalizeTC(mux.Vars(r)["tc"])
-	if err := validateTC(tc); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	tc := identity.Normalize(mux.Vars(r)["tc"])
+	if err := identity.Validate(tc); err != nil {
+		apierror.Write(w, r, apierror.InvalidTC, map[string]any{"reason": err.Error()})
 		return
 	}
 
-	allowDB.Lock()
-	delete(allowDB.ByTC, tc)
-	allowDB.Unlock()
+	api.allowDB.Lock()
+	prev, existed := api.allowDB.ByTC[tc]
+	delete(api.allowDB.ByTC, tc)
+	api.allowDB.Unlock()
 
-	if err := persist(); err != nil {
+	if err := api.persist(); err != nil {
 		log.Println("allowlist silme kaydi yazilamadi:", err)
 	}
+	log.Printf("[INFO] allowlist: %s silindi", identity.Mask(tc))
+	if existed {
+		api.recordAudit(r, "allowlist.remove", tc, prev, nil)
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"ok": true,
@@ -111,55 +168,94 @@ func RemoveAllowlist(w http.ResponseWriter, r *http.Request) {
 
 // --- Helpers ---
 
-func ensureLoaded() {
-	onceLoadAllowDB.Do(func() {
-		if err := os.MkdirAll(filepath.Dir(allowFile), 0o755); err != nil {
+func (api *API) allowFile() string { return api.currentConfig().AllowlistFile }
+
+func (api *API) ensureLoaded() {
+	api.onceLoadAllowDB.Do(func() {
+		if err := os.MkdirAll(filepath.Dir(api.allowFile()), 0o755); err != nil {
 			log.Println("data klasoru olusmadi:", err)
 		}
-		_ = loadFromDisk()
+		if err := api.loadFromDisk(); err != nil {
+			log.Println("allowlist okunamadi:", err)
+			api.allowDB.Lock()
+			api.allowLoadErr = err
+			api.allowDB.Unlock()
+		}
 	})
 }
 
-func loadFromDisk() error {
-	f, err := os.Open(allowFile)
+func (api *API) loadFromDisk() error {
+	m, err := readAllowFile(api.allowFile())
+	if err != nil {
+		return err
+	}
+	api.allowDB.Lock()
+	api.allowDB.ByTC = m
+	api.allowDB.Unlock()
+	return nil
+}
+
+func readAllowFile(path string) (map[string]allowItem, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // ilk calisma, dosya yok -> bos
+			return map[string]allowItem{}, nil // ilk calisma, dosya yok -> bos
 		}
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
 	var list []allowItem
 	if err := json.NewDecoder(f).Decode(&list); err != nil {
-		return err
+		return nil, err
 	}
 
 	m := make(map[string]allowItem, len(list))
 	for _, it := range list {
 		m[it.TC] = it
 	}
+	return m, nil
+}
 
-	allowDB.Lock()
-	allowDB.ByTC = m
-	allowDB.Unlock()
-	return nil
+func (api *API) persist() error {
+	api.allowDB.RLock()
+	defer api.allowDB.RUnlock()
+	return api.persistLocked(api.allowDB.ByTC)
 }
 
-func persist() error {
-	allowDB.RLock()
-	defer allowDB.RUnlock()
+// persistLocked writes m as the allowlist file. The caller holds allowDB's
+// lock, so no other write can land between the file and the in-memory map;
+// writers install m only after it succeeds.
+func (api *API) persistLocked(m map[string]allowItem) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("allowlist", start, err) }(time.Now())
 
 	var list []allowItem
-	for _, v := range allowDB.ByTC {
+	for _, v := range m {
 		list = append(list, v)
 	}
 
-	tmp := allowFile + ".tmp"
+	tmp := api.allowFile() + ".tmp"
 	if err := writeFileJSON(tmp, list); err != nil {
 		return err
 	}
-	return os.Rename(tmp, allowFile)
+	return os.Rename(tmp, api.allowFile())
+}
+
+// grantable reports whether caller may give someone role. Patron is only
+// granted by a patron, and no one can hand out a permission they lack.
+func grantable(p *rbac.Policy, caller, role rbac.Role) bool {
+	if caller == rbac.Patron {
+		return true
+	}
+	if role == rbac.Patron {
+		return false
+	}
+	for _, perm := range rbac.Permissions {
+		if p.Allows(role, perm) && !p.Allows(caller, perm) {
+			return false
+		}
+	}
+	return true
 }
 
 func writeFileJSON(path string, v any) error {
@@ -178,21 +274,3 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
-
-func validateTC(tc string) error {
-	if !tcRegex.MatchString(tc) {
-		return errors.New("tc 11 haneli olmalı")
-	}
-	return nil
-}
-
-func normalizeTC(tc string) string {
-	// baştaki/sondaki boşlukları at, yalnızca rakamları bırak
-	out := make([]rune, 0, len(tc))
-	for _, r := range tc {
-		if r >= '0' && r <= '9' {
-			out = append(out, r)
-		}
-	}
-	return string(out)
-}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/audit"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
+	"hys-go-backend/rbac"
+)
+
+// allowSweepInterval: süresi dolan kayıtların arşive taşınma sıklığı.
+const allowSweepInterval = time.Minute
+
+// allowView is an allowlist entry as returned by the admin API.
+type allowView struct {
+	allowItem
+	Status string `json:"status"` // active | pending | expired
+}
+
+func (it allowItem) status(now time.Time) string {
+	switch {
+	case it.ValidFrom != nil && now.Before(*it.ValidFrom):
+		return "pending"
+	case it.ValidUntil != nil && !now.Before(*it.ValidUntil):
+		return "expired"
+	}
+	return "active"
+}
+
+func (it allowItem) activeAt(now time.Time) bool { return it.status(now) == "active" }
+
+func validateWindow(from, until *time.Time, now time.Time) []apierror.FieldError {
+	var errs []apierror.FieldError
+	if until != nil && !until.After(now) {
+		errs = append(errs, apierror.FieldError{Field: "valid_until", Code: "invalid", Message: "must be in the future"})
+	}
+	if from != nil && until != nil && !until.After(*from) {
+		errs = append(errs, apierror.FieldError{Field: "valid_until", Code: "invalid", Message: "must be after valid_from"})
+	}
+	return errs
+}
+
+// AllowlistRoles makes the allowlist the only source of roles: X-Role is
+// overwritten with the role of the caller's active entry, and anyone else
+// (no session, no entry, pending or expired) is treated as personel.
+// İstemcinin gönderdiği X-Role hiçbir durumda yetki vermez.
+func (api *API) AllowlistRoles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := string(rbac.Personel)
+		if tc := actorTC(r); tc != "" {
+			role = api.allowRole(tc, time.Now())
+		}
+		r.Header.Set("X-Role", role)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowRole returns the role tc holds at now: the active allowlist entry's
+// role, otherwise personel.
+func (api *API) allowRole(tc string, now time.Time) string {
+	api.ensureLoaded()
+	api.allowDB.RLock()
+	it, ok := api.allowDB.ByTC[tc]
+	api.allowDB.RUnlock()
+	if ok && it.activeAt(now) {
+		return it.Role
+	}
+	return string(rbac.Personel)
+}
+
+// StartAllowlistSweeper archives expired entries until ctx is done.
+func (api *API) StartAllowlistSweeper(ctx context.Context) {
+	RegisterWorker("allowlist_sweeper", allowSweepInterval)
+	go func() {
+		t := time.NewTicker(allowSweepInterval)
+		defer t.Stop()
+		for {
+			start := time.Now()
+			n, err := api.sweepAllowlist(start)
+			metrics.ObserveJob("allowlist_sweep", start, err)
+			WorkerHeartbeat("allowlist_sweeper")
+			if err != nil {
+				log.Printf("[ERROR] allowlist sweep: %v", err)
+			} else if n > 0 {
+				log.Printf("[INFO] allowlist sweep: %d süresi dolmuş kayıt arşivlendi", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+type archivedAllowItem struct {
+	allowItem
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// sweepAllowlist moves entries that expired before now to the archive file.
+// The archive is written first so an entry is never lost; a failed store
+// write puts the entries back and the next run retries.
+func (api *API) sweepAllowlist(now time.Time) (int, error) {
+	api.ensureLoaded()
+
+	api.allowDB.RLock()
+	var expired []allowItem
+	for _, it := range api.allowDB.ByTC {
+		if it.status(now) == "expired" {
+			expired = append(expired, it)
+		}
+	}
+	api.allowDB.RUnlock()
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	if err := api.archiveAllowItems(expired, now); err != nil {
+		return 0, err
+	}
+
+	// Arşiv yazılırken bir yönetici kaydı yeniden eklemiş ya da süresini
+	// uzatmış olabilir; yalnızca hâlâ aynı ve süresi dolmuş kayıtlar silinir.
+	api.allowDB.Lock()
+	removed := expired[:0]
+	for _, it := range expired {
+		if cur, ok := api.allowDB.ByTC[it.TC]; ok && sameAllowItem(cur, it) && cur.status(now) == "expired" {
+			delete(api.allowDB.ByTC, it.TC)
+			removed = append(removed, it)
+		}
+	}
+	api.allowDB.Unlock()
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	if err := api.persist(); err != nil {
+		api.allowDB.Lock()
+		for _, it := range removed {
+			if _, taken := api.allowDB.ByTC[it.TC]; !taken {
+				api.allowDB.ByTC[it.TC] = it
+			}
+		}
+		api.allowDB.Unlock()
+		return 0, err
+	}
+
+	for _, it := range removed {
+		log.Printf("[INFO] allowlist: %s süresi doldu (role=%s)", identity.Mask(it.TC), it.Role)
+		api.recordSystemAudit("allowlist.expire", it.TC, it, nil)
+	}
+	return len(removed), nil
+}
+
+// sameAllowItem reports whether a and b are the same entry, field by field.
+func sameAllowItem(a, b allowItem) bool {
+	return a.TC == b.TC && a.Role == b.Role && a.Name == b.Name &&
+		a.Scope == b.Scope && a.Branch == b.Branch && slices.Equal(a.Region, b.Region) &&
+		sameTime(a.ValidFrom, b.ValidFrom) && sameTime(a.ValidUntil, b.ValidUntil) &&
+		a.GrantedBy == b.GrantedBy && a.Reason == b.Reason && a.DelegatedFrom == b.DelegatedFrom
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (api *API) allowArchiveFile() string { return api.dataPath("allowlist_archive.json") }
+
+func (api *API) archiveAllowItems(items []allowItem, now time.Time) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("allowlist_archive", start, err) }(time.Now())
+
+	var archive []archivedAllowItem
+	if b, err := os.ReadFile(api.allowArchiveFile()); err == nil {
+		if err := json.Unmarshal(b, &archive); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for _, it := range items {
+		archive = append(archive, archivedAllowItem{allowItem: it, ArchivedAt: now.UTC()})
+	}
+
+	tmp := api.allowArchiveFile() + ".tmp"
+	if err := writeFileJSON(tmp, archive); err != nil {
+		return err
+	}
+	return os.Rename(tmp, api.allowArchiveFile())
+}
+
+// recordSystemAudit is recordAudit for actions taken by background jobs.
+func (api *API) recordSystemAudit(action, target string, before, after any) {
+	api.appendAudit(audit.Entry{
+		ActorRole: "system",
+		Action:    action,
+		Target:    target,
+		Before:    rawJSON(before),
+		After:     rawJSON(after),
+	})
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/rbac"
+)
+
+// maxImportRows bounds one import; daha büyük listeler parçalanmalı.
+const maxImportRows = 5000
+
+type importRow struct {
+	TC, Role, Name string
+}
+
+// importRowError points at one bad row. Row is 1-based and counts data rows
+// only (the CSV header is not a row).
+type importRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type importSummary struct {
+	Mode      string           `json:"mode"`
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Added     []string         `json:"added"`
+	Updated   []string         `json:"updated"`
+	Removed   []string         `json:"removed"`
+	Unchanged int              `json:"unchanged"`
+	Errors    []importRowError `json:"errors"`
+}
+
+// POST /api/admin/allowlist/import?mode=merge|replace&dry_run=true&verify_enibra=false
+// Gövde: text/csv (tc,role,name başlıklı; ";" ayracı da olur) ya da
+// application/json [{"tc":"...","role":"...","name":"..."}]. Her iki biçimde de
+// export çıktısı olduğu gibi verilebilir; tc/role/name dışındaki alanlar yok sayılır.
+// Herhangi bir satır hatalıysa hiçbir değişiklik uygulanmaz. replace modu,
+// dosyada olmayan tüm kayıtları (devirler dahil) siler. Çağıranın veremeyeceği
+// roller (patron ya da sahip olmadığı bir yetkiyi taşıyan rol) satır hatasıdır.
+func (api *API) ImportAllowlist(w http.ResponseWriter, r *http.Request) {
+	api.ensureLoaded()
+
+	q := r.URL.Query()
+	mode := strings.ToLower(strings.TrimSpace(q.Get("mode")))
+	if mode == "" {
+		mode = "merge"
+	}
+	var fields []apierror.FieldError
+	if mode != "merge" && mode != "replace" {
+		fields = append(fields, apierror.FieldError{Field: "mode", Code: "invalid", Message: "merge | replace"})
+	}
+	dryRun, err := queryBool(q, "dry_run", false)
+	if err != nil {
+		fields = append(fields, apierror.FieldError{Field: "dry_run", Code: "invalid", Message: "true | false"})
+	}
+	verifyEnibra, err := queryBool(q, "verify_enibra", true)
+	if err != nil {
+		fields = append(fields, apierror.FieldError{Field: "verify_enibra", Code: "invalid", Message: "true | false"})
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": fields})
+		return
+	}
+
+	rows, ok := readImportRows(w, r)
+	if !ok {
+		return
+	}
+	if len(rows) > maxImportRows {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: "rows", Code: "too_long", Message: "at most " + strconv.Itoa(maxImportRows),
+		}}})
+		return
+	}
+
+	var known map[string]struct{}
+	if verifyEnibra {
+		known, err = api.enibraTCSet(r.Context())
+		if err != nil {
+			log.Printf("[enibra] allowlist import: %v", err)
+			apierror.Write(w, r, apierror.EnibraUpstream, map[string]any{"hint": "verify_enibra=false ile Enibra kontrolü atlanabilir"})
+			return
+		}
+	}
+
+	items, rowErrs := validateImportRows(rows, known, api.Policy(), requestRole(r))
+	sum := importSummary{
+		Mode: mode, DryRun: dryRun, Total: len(rows),
+		Added: []string{}, Updated: []string{}, Removed: []string{}, Errors: rowErrs,
+	}
+
+	actor := actorTC(r)
+	api.allowDB.Lock()
+	prev := api.allowDB.ByTC
+	next := make(map[string]allowItem, len(prev)+len(items))
+	if mode == "merge" {
+		for tc, it := range prev {
+			next[tc] = it
+		}
+	}
+	for _, in := range items {
+		old, existed := prev[in.TC]
+		switch {
+		case !existed:
+			in.GrantedBy = actor
+			next[in.TC] = in
+			sum.Added = append(sum.Added, in.TC)
+		case old.Role == in.Role && old.Name == in.Name:
+			next[in.TC] = old
+			sum.Unchanged++
+		default:
+			// rol/isim dışındaki alanlar (süre, kapsam, devir) korunur
+			old.Role, old.Name, old.GrantedBy = in.Role, in.Name, actor
+			next[in.TC] = old
+			sum.Updated = append(sum.Updated, in.TC)
+		}
+	}
+	if mode == "replace" {
+		for tc := range prev {
+			if _, keep := next[tc]; !keep {
+				sum.Removed = append(sum.Removed, tc)
+			}
+		}
+	}
+	sort.Strings(sum.Added)
+	sort.Strings(sum.Updated)
+	sort.Strings(sum.Removed)
+
+	if dryRun || len(rowErrs) > 0 {
+		api.allowDB.Unlock()
+		if len(rowErrs) > 0 && !dryRun {
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"rows": rowErrs})
+			return
+		}
+		writeJSON(w, http.StatusOK, sum)
+		return
+	}
+
+	// Dosya kilit altında yazılır ve harita yalnızca başarıda değişir; arada
+	// gelen başka bir değişiklik ne ezilir ne de yarım kalır.
+	if err := api.persistLocked(next); err != nil {
+		api.allowDB.Unlock()
+		log.Println("allowlist import kaydedilemedi:", err)
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	api.allowDB.ByTC = next
+	api.allowDB.Unlock()
+
+	log.Printf("[INFO] allowlist import (%s): +%d ~%d -%d", mode, len(sum.Added), len(sum.Updated), len(sum.Removed))
+	api.recordAudit(r, "allowlist.import", "", nil, map[string]any{
+		"mode": mode, "added": sum.Added, "updated": sum.Updated, "removed": sum.Removed,
+	})
+	writeJSON(w, http.StatusOK, sum)
+}
+
+// readImportRows parses the body as CSV or JSON depending on Content-Type.
+func readImportRows(w http.ResponseWriter, r *http.Request) ([]importRow, bool) {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		// JSON export tam allowItem yazar; aynı şekil okunur, sunucunun
+		// yönettiği alanlar (granted_by, süre, kapsam...) kullanılmaz.
+		var list []allowItem
+		if !decodeJSON(w, r, &list) {
+			return nil, false
+		}
+		rows := make([]importRow, len(list))
+		for i, it := range list {
+			rows[i] = importRow{TC: it.TC, Role: it.Role, Name: it.Name}
+		}
+		return rows, true
+	case mt == "text/csv" || mt == "application/csv":
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				apierror.Write(w, r, apierror.BodyTooLarge, map[string]any{"limit_bytes": maxErr.Limit})
+			} else {
+				apierror.Write(w, r, apierror.InvalidRequest, nil)
+			}
+			return nil, false
+		}
+		rows, err := parseImportCSV(body)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"reason": err.Error()})
+			return nil, false
+		}
+		return rows, true
+	default:
+		apierror.Write(w, r, apierror.UnsupportedMedia, map[string]any{
+			"content_type": r.Header.Get("Content-Type"), "accepted": []string{"text/csv", "application/json"},
+		})
+		return nil, false
+	}
+}
+
+// parseImportCSV reads tc,role,name. A header row is optional; with one,
+// columns may be in any order and unknown columns (e.g. from export) are
+// ignored. Excel'in BOM'u ve ";" ayracı desteklenir.
+func parseImportCSV(body []byte) ([]importRow, error) {
+	body = bytes.TrimPrefix(body, []byte("\ufeff"))
+	cr := csv.NewReader(bytes.NewReader(body))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	firstLine, _, _ := bytes.Cut(body, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{"tc": 0, "role": 1, "name": 2}
+	if len(records) > 0 && hasHeader(records[0]) {
+		col = map[string]int{}
+		for i, h := range records[0] {
+			col[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		if _, ok := col["tc"]; !ok {
+			return nil, errors.New("csv header has no tc column")
+		}
+		records = records[1:]
+	}
+
+	cell := func(rec []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+	rows := make([]importRow, 0, len(records))
+	for _, rec := range records {
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		rows = append(rows, importRow{TC: cell(rec, "tc"), Role: cell(rec, "role"), Name: cell(rec, "name")})
+	}
+	return rows, nil
+}
+
+func hasHeader(rec []string) bool {
+	for _, h := range rec {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "tc", "role", "name":
+			return true
+		}
+	}
+	return false
+}
+
+// validateImportRows normalizes rows and collects every problem. known is
+// the set of TCs in Enibra; nil skips that check. Roles the caller could not
+// grant (bkz. grantable) are row errors.
+func validateImportRows(rows []importRow, known map[string]struct{}, p *rbac.Policy, caller rbac.Role) ([]allowItem, []importRowError) {
+	var (
+		items []allowItem
+		errs  = []importRowError{}
+		seen  = map[string]int{}
+	)
+	for i, row := range rows {
+		n := i + 1
+		bad := false
+		fail := func(field, code, msg string) {
+			errs = append(errs, importRowError{Row: n, Field: field, Code: code, Message: msg})
+			bad = true
+		}
+
+		tc := identity.Normalize(row.TC)
+		switch err := identity.Validate(tc); {
+		case strings.TrimSpace(row.TC) == "":
+			fail("tc", "required", "")
+		case err != nil:
+			fail("tc", "invalid", err.Error())
+		case seen[tc] > 0:
+			fail("tc", "duplicate", "same tc on row "+strconv.Itoa(seen[tc]))
+		default:
+			seen[tc] = n
+			if known != nil {
+				if _, ok := known[tc]; !ok {
+					fail("tc", "not_in_enibra", "")
+				}
+			}
+		}
+
+		roleName := row.Role
+		if strings.TrimSpace(roleName) == "" {
+			roleName = string(defaultRole)
+		}
+		role, ok := rbac.ParseRole(roleName)
+		switch {
+		case !ok:
+			fail("role", "invalid", roleChoices())
+		case !grantable(p, caller, role):
+			fail("role", "forbidden", "")
+		}
+		if len([]rune(row.Name)) > 200 {
+			fail("name", "too_long", "")
+		}
+
+		if !bad {
+			items = append(items, allowItem{TC: tc, Role: string(role), Name: strings.TrimSpace(row.Name)})
+		}
+	}
+	return items, errs
+}
+
+// enibraTCSet returns every TC in the Enibra personnel list.
+func (api *API) enibraTCSet(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := api.enibraPersonnel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		if tc := rowTC(row); tc != "" {
+			set[tc] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+// GET /api/admin/allowlist/export?format=csv|json
+// Her iki çıktı da import'a olduğu gibi geri verilebilir.
+func (api *API) ExportAllowlist(w http.ResponseWriter, r *http.Request) {
+	api.ensureLoaded()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: "format", Code: "invalid", Message: "csv | json",
+		}}})
+		return
+	}
+
+	api.allowDB.RLock()
+	list := make([]allowItem, 0, len(api.allowDB.ByTC))
+	for _, it := range api.allowDB.ByTC {
+		list = append(list, it)
+	}
+	api.allowDB.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].TC < list[j].TC })
+
+	name := "allowlist-" + time.Now().Format("20060102") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	api.recordAudit(r, "allowlist.export", "", nil, map[string]any{"format": format, "count": len(list)})
+
+	if format == "json" {
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"tc", "role", "name", "scope", "branch", "valid_from", "valid_until", "delegated_from", "reason"})
+	for _, it := range list {
+		_ = cw.Write([]string{
+			it.TC, it.Role, it.Name, it.Scope, it.Branch,
+			formatOptionalTime(it.ValidFrom), formatOptionalTime(it.ValidUntil),
+			it.DelegatedFrom, it.Reason,
+		})
+	}
+	cw.Flush()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func queryBool(q url.Values, key string, def bool) (bool, error) {
+	v := strings.TrimSpace(q.Get(key))
+	if v == "" {
+		return def, nil
+	}
+	return strconv.ParseBool(v)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hys-go-backend/config"
+	"hys-go-backend/rbac"
+)
+
+func TestGrantable(t *testing.T) {
+	p := mustPolicy(t)
+	tests := []struct {
+		caller, role rbac.Role
+		want         bool
+	}{
+		{rbac.Patron, rbac.Patron, true},
+		{rbac.Patron, rbac.Admin, true},
+		{rbac.Admin, rbac.Patron, false},
+		{rbac.Admin, rbac.Admin, true},
+		{rbac.Admin, rbac.Manager, false}, // şube yetkileri admin'de yok
+		{rbac.Admin, rbac.Personel, true},
+		{rbac.IK, rbac.Admin, false}, // admin'in ik'da olmayan yetkileri var
+		{rbac.Manager, rbac.Admin, false},
+	}
+	for _, tt := range tests {
+		if got := grantable(p, tt.caller, tt.role); got != tt.want {
+			t.Errorf("grantable(%s, %s) = %v, want %v", tt.caller, tt.role, got, tt.want)
+		}
+	}
+}
+
+func mustPolicy(t *testing.T) *rbac.Policy {
+	t.Helper()
+	p, err := rbac.NewPolicy(filepath.Join(t.TempDir(), "roles.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAddAllowlist(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     rbac.Role
+		body       string
+		badFile    bool
+		wantStatus int
+		wantRole   string // kayıttan sonra 25031519376'nın rolü; "" kayıt yok
+	}{
+		{"admin adds admin", rbac.Admin, `{"tc":"25031519376","role":"admin"}`, false, http.StatusCreated, "admin"},
+		{"admin cannot grant patron", rbac.Admin, `{"tc":"25031519376","role":"patron"}`, false, http.StatusForbidden, ""},
+		{"patron grants patron", rbac.Patron, `{"tc":"25031519376","role":"patron"}`, false, http.StatusCreated, "patron"},
+		{"write failure changes nothing", rbac.Patron, `{"tc":"25031519376","role":"admin"}`, true, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "allowlist.json")
+			if tt.badFile {
+				file = filepath.Join(dir, "missing", "allowlist.json")
+			}
+			api := New(&config.Config{DataDir: dir, AllowlistFile: file})
+			api.onceLoadAllowDB.Do(func() {}) // dosya yok; boş liste
+
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/allowlist", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(ActorHeader, "10000000146")
+			req.Header.Set("X-Role", string(tt.caller))
+			rec := httptest.NewRecorder()
+			api.AddAllowlist(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			got := api.allowDB.ByTC["25031519376"].Role
+			if got != tt.wantRole {
+				t.Errorf("role in memory = %q, want %q", got, tt.wantRole)
+			}
+			if tt.badFile {
+				return
+			}
+			onDisk, err := readAllowFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if onDisk["25031519376"].Role != tt.wantRole {
+				t.Errorf("role on disk = %q, want %q", onDisk["25031519376"].Role, tt.wantRole)
+			}
+		})
+	}
+}
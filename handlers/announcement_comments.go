@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/metrics"
+	"hys-go-backend/rbac"
+)
+
+// reactionEmojis is the fixed reaction set. Karşılaştırma U+FE0F (emoji
+// varyasyon seçicisi) olmadan yapılır; "❤" ve "❤️" aynı tepkidir.
+var reactionEmojis = []string{"👍", "❤️", "🎉", "😂", "😮", "😢", "🙏", "👏"}
+
+// Comment is one comment or reply. Yanıtlar tek seviyelidir: ParentID her
+// zaman üst düzey bir yorumu gösterir.
+type Comment struct {
+	ID         string `json:"id"`
+	ParentID   string `json:"parent_id,omitempty"`
+	AuthorTC   string `json:"author_tc,omitempty"` // yalnızca yazara ve moderatöre gösterilir
+	AuthorName string `json:"author_name,omitempty"`
+	Body       string `json:"body"`
+	CreatedAt  string `json:"created_at"`
+
+	HiddenAt   string `json:"hidden_at,omitempty"`
+	HiddenBy   string `json:"hidden_by,omitempty"`
+	HideReason string `json:"hide_reason,omitempty"`
+	DeletedAt  string `json:"deleted_at,omitempty"`
+	DeletedBy  string `json:"deleted_by,omitempty"`
+
+	// Yanıtlarda doldurulur, saklanmaz.
+	Mine    bool      `json:"mine,omitempty"`
+	Hidden  bool      `json:"hidden,omitempty"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Replies []Comment `json:"replies,omitempty"`
+}
+
+func (c Comment) visible() bool { return c.HiddenAt == "" && c.DeletedAt == "" }
+
+// annThread holds the comments and reactions of one announcement.
+type annThread struct {
+	Comments  []Comment           `json:"comments,omitempty"`
+	Reactions map[string][]string `json:"reactions,omitempty"` // emoji -> TC listesi
+	UpdatedAt string              `json:"updated_at,omitempty"`
+}
+
+// Engagement is the aggregate shown with each announcement in the feed.
+type Engagement struct {
+	Comments    int            `json:"comments"`
+	Reactions   map[string]int `json:"reactions,omitempty"`
+	MyReactions []string       `json:"my_reactions,omitempty"`
+}
+
+// commentMu guards announcement_comments.json: duyuru ID -> annThread.
+var commentMu sync.Mutex
+
+func (api *API) commentFile() string { return api.dataPath("announcement_comments.json") }
+
+// engagement counts visible comments and reactions; tc's own reactions are
+// listed separately.
+func (t *annThread) engagement(tc string) *Engagement {
+	e := &Engagement{}
+	if t == nil {
+		return e
+	}
+	for _, c := range t.Comments {
+		if c.visible() {
+			e.Comments++
+		}
+	}
+	for _, emoji := range reactionEmojis {
+		tcs := t.Reactions[emoji]
+		if len(tcs) == 0 {
+			continue
+		}
+		if e.Reactions == nil {
+			e.Reactions = map[string]int{}
+		}
+		e.Reactions[emoji] = len(tcs)
+		if tc != "" && containsFold(tcs, tc) {
+			e.MyReactions = append(e.MyReactions, emoji)
+		}
+	}
+	return e
+}
+
+func (t *annThread) changedSince(since time.Time) bool {
+	if t == nil {
+		return false
+	}
+	at, ok := parseAnnTime(t.UpdatedAt)
+	return ok && at.After(since)
+}
+
+// commentsLastModified is the latest comment or reaction change on any
+// announcement; feed'in Last-Modified değeri sayılar değişince de ilerler.
+func commentsLastModified(threads map[string]*annThread) time.Time {
+	var last time.Time
+	for _, t := range threads {
+		if at, ok := parseAnnTime(t.UpdatedAt); ok && at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
+// view shapes the thread for the caller: replies under their parent,
+// hidden comments without body for non-moderators, deleted comments only
+// as placeholders that keep their replies in place.
+func (t *annThread) view(tc string, moderator bool) []Comment {
+	out := make([]Comment, 0)
+	if t == nil {
+		return out
+	}
+	replies := map[string][]Comment{}
+	for _, c := range t.Comments {
+		if c.ParentID != "" {
+			if c, ok := c.present(tc, moderator); ok {
+				replies[c.ParentID] = append(replies[c.ParentID], c)
+			}
+		}
+	}
+	for _, c := range t.Comments {
+		if c.ParentID != "" {
+			continue
+		}
+		c.Replies = replies[c.ID]
+		if c.DeletedAt != "" && len(c.Replies) == 0 {
+			continue
+		}
+		c, _ = c.present(tc, moderator)
+		out = append(out, c)
+	}
+	return out
+}
+
+func (c Comment) present(tc string, moderator bool) (Comment, bool) {
+	c.Mine = tc != "" && c.AuthorTC == tc
+	c.Hidden, c.Deleted = c.HiddenAt != "", c.DeletedAt != ""
+	if c.Deleted {
+		if c.ParentID != "" {
+			return c, false
+		}
+		return Comment{ID: c.ID, CreatedAt: c.CreatedAt, Deleted: true, Replies: c.Replies}, true
+	}
+	if moderator {
+		return c, true
+	}
+	if !c.Mine {
+		c.AuthorTC = ""
+	}
+	if c.Hidden {
+		c.Body, c.HideReason = "", ""
+	}
+	c.HiddenAt, c.HiddenBy = "", ""
+	return c, true
+}
+
+func (api *API) isModerator(r *http.Request) bool {
+	return api.Policy().Allows(requestRole(r), rbac.AnnouncementModerate)
+}
+
+// GET /api/announcements/{id}/comments
+func (api *API) ListComments(w http.ResponseWriter, r *http.Request) {
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	commentMu.Lock()
+	threads, err := api.readComments()
+	commentMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	t := threads[a.ID]
+	respondJSON(w, http.StatusOK, map[string]any{
+		"announcement_id":   a.ID,
+		"comments_disabled": a.CommentsDisabled,
+		"engagement":        t.engagement(actorTC(r)),
+		"comments":          t.view(actorTC(r), api.isModerator(r)),
+	})
+}
+
+type commentInput struct {
+	Body     string `json:"body"`
+	ParentID string `json:"parent_id"`
+}
+
+func (in *commentInput) Validate() []apierror.FieldError {
+	in.Body = strings.TrimSpace(in.Body)
+	var errs []apierror.FieldError
+	errs = requireField(errs, "body", in.Body)
+	return maxLenField(errs, "body", in.Body, 2000)
+}
+
+// POST /api/announcements/{id}/comments {"body": "...", "parent_id": "..."}
+// Yorum düz metindir; parent_id verilirse üst düzey bir yoruma yanıttır.
+func (api *API) CreateComment(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	var in commentInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	if a.CommentsDisabled {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "comments are disabled"})
+		return
+	}
+
+	c := Comment{
+		ID: newShortID(), ParentID: in.ParentID, AuthorTC: tc, AuthorName: api.commenterName(r),
+		Body: in.Body, CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	var parentErr string
+	err := api.updateThread(a.ID, func(t *annThread) bool {
+		if c.ParentID != "" {
+			i := commentIndex(t.Comments, c.ParentID)
+			switch {
+			case i < 0 || t.Comments[i].DeletedAt != "":
+				parentErr = "not found"
+				return false
+			case t.Comments[i].ParentID != "":
+				parentErr = "replies cannot be nested"
+				return false
+			}
+		}
+		t.Comments = append(t.Comments, c)
+		return true
+	})
+	if parentErr != "" {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{
+			{Field: "parent_id", Code: "invalid", Message: parentErr},
+		}})
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	c, _ = c.present(tc, api.isModerator(r))
+	respondJSON(w, http.StatusCreated, c)
+}
+
+// commenterName is the caller's name from Enibra, or the allowlist name.
+func (api *API) commenterName(r *http.Request) string {
+	if row := api.newViewer(r).enibraRecord(); row != nil {
+		ad := strings.TrimSpace(anyToString(firstNonEmpty(row, "ADI", "AD", "ad")))
+		soyad := strings.TrimSpace(anyToString(firstNonEmpty(row, "SOYADI", "SOYAD", "soyad")))
+		if name := strings.TrimSpace(ad + " " + soyad); name != "" {
+			return name
+		}
+	}
+	api.ensureLoaded()
+	api.allowDB.RLock()
+	defer api.allowDB.RUnlock()
+	return api.allowDB.ByTC[actorTC(r)].Name
+}
+
+// DELETE /api/announcements/{id}/comments/{cid}
+// Yazar kendi yorumunu, announcement.moderate herkesinkini siler. Yanıtı
+// olan yorum yer tutucu olarak kalır.
+func (api *API) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	api.moderateComment(w, r, "announcement.comment_delete", func(c *Comment, by string, moderator bool) bool {
+		if !moderator && (by == "" || c.AuthorTC != by) {
+			return false
+		}
+		c.DeletedAt, c.DeletedBy = time.Now().UTC().Format(time.RFC3339), by
+		return true
+	})
+}
+
+type hideInput struct {
+	Reason string `json:"reason"`
+}
+
+func (in *hideInput) Validate() []apierror.FieldError {
+	return maxLenField(nil, "reason", in.Reason, 500)
+}
+
+// POST /api/announcements/{id}/comments/{cid}/hide {"reason": "..."} -> announcement.moderate
+// Gizlenen yorumun metni yalnızca moderatörlere görünür.
+func (api *API) HideComment(w http.ResponseWriter, r *http.Request) {
+	var in hideInput
+	if r.ContentLength != 0 && !decodeJSON(w, r, &in) {
+		return
+	}
+	api.moderateComment(w, r, "announcement.comment_hide", func(c *Comment, by string, _ bool) bool {
+		c.HiddenAt, c.HiddenBy, c.HideReason = time.Now().UTC().Format(time.RFC3339), by, in.Reason
+		return true
+	})
+}
+
+// POST /api/announcements/{id}/comments/{cid}/unhide -> announcement.moderate
+func (api *API) UnhideComment(w http.ResponseWriter, r *http.Request) {
+	api.moderateComment(w, r, "announcement.comment_unhide", func(c *Comment, _ string, _ bool) bool {
+		c.HiddenAt, c.HiddenBy, c.HideReason = "", "", ""
+		return true
+	})
+}
+
+// moderateComment applies fn to one comment; fn returns false when the
+// caller may not change it. Moderatör işlemleri denetim kaydına düşer.
+func (api *API) moderateComment(w http.ResponseWriter, r *http.Request, action string, fn func(c *Comment, by string, moderator bool) bool) {
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	cid, by, moderator := mux.Vars(r)["cid"], actorTC(r), api.isModerator(r)
+
+	var before, after Comment
+	found, allowed := false, false
+	err := api.updateThread(a.ID, func(t *annThread) bool {
+		i := commentIndex(t.Comments, cid)
+		if i < 0 || t.Comments[i].DeletedAt != "" {
+			return false
+		}
+		found = true
+		before = t.Comments[i]
+		allowed = fn(&t.Comments[i], by, moderator)
+		after = t.Comments[i]
+		return allowed
+	})
+	switch {
+	case !found:
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	case !allowed:
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"permission": rbac.AnnouncementModerate})
+		return
+	case err != nil:
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	if moderator && before.AuthorTC != by {
+		api.recordAudit(r, action, a.ID+"/"+cid, moderationState(before), moderationState(after))
+	}
+	if after.DeletedAt != "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	after, _ = after.present(by, moderator)
+	respondJSON(w, http.StatusOK, after)
+}
+
+func moderationState(c Comment) map[string]any {
+	return map[string]any{
+		"author_tc": c.AuthorTC, "body": c.Body,
+		"hidden_at": c.HiddenAt, "hide_reason": c.HideReason, "deleted_at": c.DeletedAt,
+	}
+}
+
+// PUT    /api/announcements/{id}/reactions/{emoji}   tepki ekler
+// DELETE /api/announcements/{id}/reactions/{emoji}   tepkiyi kaldırır
+// Her ikisi de idempotenttir ve güncel sayıları döner.
+func (api *API) SetReaction(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	emoji, ok := parseReaction(mux.Vars(r)["emoji"])
+	if !ok {
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"param": "emoji", "allowed": reactionEmojis})
+		return
+	}
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+
+	add := r.Method == http.MethodPut
+	var eng *Engagement
+	err := api.updateThread(a.ID, func(t *annThread) bool {
+		tcs := t.Reactions[emoji]
+		has := containsFold(tcs, tc)
+		changed := has != add
+		switch {
+		case changed && add:
+			if t.Reactions == nil {
+				t.Reactions = map[string][]string{}
+			}
+			t.Reactions[emoji] = append(tcs, tc)
+		case changed:
+			kept := tcs[:0]
+			for _, v := range tcs {
+				if v != tc {
+					kept = append(kept, v)
+				}
+			}
+			t.Reactions[emoji] = kept
+			if len(kept) == 0 {
+				delete(t.Reactions, emoji)
+			}
+		}
+		eng = t.engagement(tc)
+		return changed
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	respondJSON(w, http.StatusOK, eng)
+}
+
+func parseReaction(s string) (string, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "\uFE0F", "")
+	for _, emoji := range reactionEmojis {
+		if s == strings.ReplaceAll(emoji, "\uFE0F", "") {
+			return emoji, true
+		}
+	}
+	return "", false
+}
+
+func commentIndex(comments []Comment, id string) int {
+	for i := range comments {
+		if comments[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateThread runs fn on the thread of id and writes the store when fn
+// reports a change.
+func (api *API) updateThread(id string, fn func(t *annThread) bool) error {
+	commentMu.Lock()
+	defer commentMu.Unlock()
+
+	threads, err := api.readComments()
+	if err != nil {
+		return err
+	}
+	t := threads[id]
+	if t == nil {
+		t = &annThread{}
+	}
+	if !fn(t) {
+		return nil
+	}
+	t.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	threads[id] = t
+	return api.writeComments(threads)
+}
+
+// commentSnapshot reads every thread for the feed.
+func (api *API) commentSnapshot() (map[string]*annThread, error) {
+	commentMu.Lock()
+	defer commentMu.Unlock()
+	return api.readComments()
+}
+
+func (api *API) readComments() (map[string]*annThread, error) {
+	b, err := os.ReadFile(api.commentFile())
+	if os.IsNotExist(err) {
+		return map[string]*annThread{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	threads := map[string]*annThread{}
+	if err := json.Unmarshal(b, &threads); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+func (api *API) writeComments(threads map[string]*annThread) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("announcement_comments", start, err) }(time.Now())
+
+	b, err := json.Marshal(threads)
+	if err != nil {
+		return err
+	}
+	tmp := api.commentFile() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, api.commentFile())
+}
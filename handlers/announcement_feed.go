@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/markdown"
+)
+
+const (
+	feedDefaultLimit = 50
+	feedMaxLimit     = 200
+)
+
+// annCache keeps the parsed announcements file for the read-only feed so
+// ListAnnouncements neither re-parses the file nor waits on annMu. It is
+// dropped when writeAnnouncements bumps annCacheGen or the file changes on
+// disk. Cached items are shared: callers must not modify them.
+var (
+	annCacheGen atomic.Uint64
+	annCache    struct {
+		sync.Mutex
+		items   []Announcement
+		gen     uint64
+		modTime time.Time
+		size    int64
+	}
+)
+
+func (api *API) annSnapshot() ([]Announcement, error) {
+	api.ensureAnnFile()
+	st, err := os.Stat(api.annFile())
+	if err != nil {
+		return nil, err
+	}
+	gen := annCacheGen.Load()
+
+	annCache.Lock()
+	defer annCache.Unlock()
+	if annCache.items != nil && annCache.gen == gen && annCache.modTime.Equal(st.ModTime()) && annCache.size == st.Size() {
+		return annCache.items, nil
+	}
+	// Dosya rename ile bütün olarak değiştiği için annMu gerekmez.
+	items, err := api.readAnnouncements()
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []Announcement{}
+	}
+	annCache.items, annCache.gen, annCache.modTime, annCache.size = items, gen, st.ModTime(), st.Size()
+	return items, nil
+}
+
+type feedQuery struct {
+	terms    []string // foldTR ile katlanmış, hepsi eşleşmeli
+	archived bool
+	limit    int
+	after    *feedCursor
+	since    time.Time
+	hasSince bool
+}
+
+// parseFeedQuery reads q, archived, limit, cursor and since. On failure the
+// error response has been written.
+func parseFeedQuery(w http.ResponseWriter, r *http.Request) (feedQuery, bool) {
+	qs := r.URL.Query()
+	fq := feedQuery{limit: feedDefaultLimit}
+	bad := func(param, reason string) (feedQuery, bool) {
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"param": param, "reason": reason})
+		return fq, false
+	}
+
+	fq.terms = strings.Fields(foldTR(qs.Get("q")))
+	fq.archived, _ = strconv.ParseBool(qs.Get("archived"))
+	if len(fq.terms) > 0 {
+		fq.archived = true
+	}
+	if s := qs.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > feedMaxLimit {
+			return bad("limit", "1-"+strconv.Itoa(feedMaxLimit))
+		}
+		fq.limit = n
+	}
+	if s := qs.Get("cursor"); s != "" {
+		c, ok := decodeFeedCursor(s)
+		if !ok {
+			return bad("cursor", "invalid cursor")
+		}
+		fq.after = &c
+	}
+	if s := qs.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return bad("since", "RFC3339 time expected")
+		}
+		fq.since, fq.hasSince = t, true
+	}
+	return fq, true
+}
+
+// matches reports whether every search term occurs in the title, the
+// plain-text body or an attachment name.
+func (fq feedQuery) matches(a Announcement) bool {
+	if len(fq.terms) == 0 {
+		return true
+	}
+	names := make([]string, 0, len(a.Attachments))
+	for _, att := range a.Attachments {
+		names = append(names, att.Name)
+	}
+	hay := foldTR(a.Title + "\n" + markdown.Text(a.Body) + "\n" + strings.Join(names, "\n"))
+	for _, t := range fq.terms {
+		if !strings.Contains(hay, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// page cuts sorted items after the cursor to the limit and returns the
+// cursor for the next page ("" on the last page).
+func (fq feedQuery) page(items []Announcement) ([]Announcement, string) {
+	if fq.after != nil {
+		i := 0
+		for i < len(items) && !fq.after.before(items[i]) {
+			i++
+		}
+		items = items[i:]
+	}
+	if len(items) <= fq.limit {
+		return items, ""
+	}
+	items = items[:fq.limit]
+	return items, encodeFeedCursor(items[len(items)-1])
+}
+
+// feedCursor is the sort key of the last item of a page.
+type feedCursor struct {
+	Pinned    bool   `json:"p"`
+	CreatedAt string `json:"c"`
+	ID        string `json:"i"`
+}
+
+func encodeFeedCursor(a Announcement) string {
+	b, _ := json.Marshal(feedCursor{a.Pinned, a.CreatedAt, a.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeFeedCursor(s string) (feedCursor, bool) {
+	var c feedCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == "" {
+		return c, false
+	}
+	return c, true
+}
+
+// before reports whether the cursor sorts before a, i.e. a belongs to a
+// later page (sortAnnouncements sırası).
+func (c feedCursor) before(a Announcement) bool {
+	if c.Pinned != a.Pinned {
+		return c.Pinned
+	}
+	if c.CreatedAt != a.CreatedAt {
+		return c.CreatedAt > a.CreatedAt
+	}
+	return c.ID > a.ID
+}
+
+// changedSince reports whether a changed after t in a way a synced client
+// must see: edits, deletion, or reaching publish_at / expires_at.
+func (a Announcement) changedSince(t, now time.Time) bool {
+	for _, s := range []string{a.CreatedAt, a.UpdatedAt, a.PublishedAt, a.DeletedAt} {
+		if at, ok := parseAnnTime(s); ok && at.After(t) {
+			return true
+		}
+	}
+	for _, s := range []string{a.PublishAt, a.ExpiresAt} {
+		if at, ok := parseAnnTime(s); ok && at.After(t) && !at.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// tombstone tells a since= client to drop a: only the id and, when
+// known, why it left the feed.
+func (a Announcement) tombstone() Announcement {
+	return Announcement{
+		ID: a.ID, CreatedAt: a.CreatedAt, Pinned: a.Pinned, // sıralama ve imleç için
+		DeletedAt: a.DeletedAt, ExpiresAt: a.ExpiresAt, Archived: a.Archived,
+	}
+}
+
+// feedLastModified is the latest change to the feed as a whole, including
+// scheduled items that became visible or expired on their own.
+func feedLastModified(items []Announcement, now time.Time) time.Time {
+	var last time.Time
+	for _, a := range items {
+		for _, s := range []string{a.CreatedAt, a.UpdatedAt, a.PublishedAt, a.DeletedAt, a.PublishAt, a.ExpiresAt} {
+			if at, ok := parseAnnTime(s); ok && at.After(last) && !at.After(now) {
+				last = at
+			}
+		}
+	}
+	return last
+}
+
+// writeFeed writes the page with ETag, Last-Modified and the next-page
+// cursor, or 304 when the client's copy is current. The ETag covers the
+// exact bytes, so it differs per viewer and per signed-URL window.
+func writeFeed(w http.ResponseWriter, r *http.Request, page []Announcement, next string, lastMod time.Time) {
+	body, err := json.Marshal(page)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal, nil)
+		return
+	}
+	sum := sha256.Sum256(append(body, next...))
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "private, no-cache")
+	h.Add("Vary", AuthHeader)
+	h.Add("Vary", "X-Role")
+	if !lastMod.IsZero() {
+		h.Set("Last-Modified", lastMod.UTC().Format(http.TimeFormat))
+	}
+	if next != "" {
+		h.Set("X-Next-Cursor", next)
+		q := r.URL.Query()
+		q.Set("cursor", next)
+		h.Set("Link", "<"+(&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()+`>; rel="next"`)
+	}
+
+	if notModified(r, etag, lastMod) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// notModified applies If-None-Match, or If-Modified-Since when no
+// If-None-Match was sent (RFC 9110 13.2.2).
+func notModified(r *http.Request, etag string, lastMod time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+			if t == etag || t == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastMod.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !lastMod.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// foldTR lower-cases with Turkish rules (I→ı, İ→i) and then drops the
+// Turkish diacritics, so "ŞUBE", "şube" and "sube" all match.
+func foldTR(s string) string {
+	s = strings.Map(unicode.TurkishCase.ToLower, s)
+	return trFolder.Replace(s)
+}
+
+var trFolder = strings.NewReplacer("ı", "i", "ğ", "g", "ü", "u", "ş", "s", "ö", "o", "ç", "c", "â", "a", "î", "i", "û", "u")
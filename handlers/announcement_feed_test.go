@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// feedFixture has pinned and unpinned items, several sharing a CreatedAt,
+// so the order depends on every part of the sort key.
+func feedFixture() []Announcement {
+	var items []Announcement
+	for i := 0; i < 23; i++ {
+		items = append(items, Announcement{
+			ID:        fmt.Sprintf("a%02d", i),
+			Pinned:    i%5 == 0,
+			CreatedAt: time.Date(2024, 5, 1, 9, i/3, 0, 0, time.UTC).Format(time.RFC3339), // üçer üçer aynı an
+		})
+	}
+	sortAnnouncements(items)
+	return items
+}
+
+func ids(items []Announcement) string {
+	s := make([]string, len(items))
+	for i, a := range items {
+		s[i] = a.ID
+	}
+	return strings.Join(s, ",")
+}
+
+func TestSortAnnouncements(t *testing.T) {
+	items := feedFixture()
+	for i := 1; i < len(items); i++ {
+		a, b := items[i-1], items[i]
+		if !cursorOf(a).before(b) {
+			t.Fatalf("%s (pinned %v, %s) sorted before %s (pinned %v, %s)", a.ID, a.Pinned, a.CreatedAt, b.ID, b.Pinned, b.CreatedAt)
+		}
+	}
+	if !items[0].Pinned || items[len(items)-1].Pinned {
+		t.Errorf("pinned items not first: %s", ids(items))
+	}
+}
+
+func cursorOf(a Announcement) feedCursor {
+	c, ok := decodeFeedCursor(encodeFeedCursor(a))
+	if !ok {
+		panic("cursor does not round-trip")
+	}
+	return c
+}
+
+func TestFeedPaging(t *testing.T) {
+	all := feedFixture()
+	for limit := 1; limit <= len(all)+1; limit++ {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var got []Announcement
+			fq := feedQuery{limit: limit}
+			for pages := 0; ; pages++ {
+				if pages > len(all) {
+					t.Fatal("paging does not terminate")
+				}
+				page, next := fq.page(all)
+				if len(page) > limit {
+					t.Fatalf("page of %d items, limit %d", len(page), limit)
+				}
+				got = append(got, page...)
+				if next == "" {
+					break
+				}
+				c, ok := decodeFeedCursor(next)
+				if !ok {
+					t.Fatalf("bad cursor %q", next)
+				}
+				fq.after = &c
+			}
+			if ids(got) != ids(all) {
+				t.Errorf("paged\n %s\nwant\n %s", ids(got), ids(all))
+			}
+		})
+	}
+}
+
+func TestFeedPagingWhileItemsChange(t *testing.T) {
+	all := feedFixture()
+	fq := feedQuery{limit: 5}
+	first, next := fq.page(all)
+	c, _ := decodeFeedCursor(next)
+	fq.after = &c
+
+	// İmleçteki kayıt silindi, başa yeni bir kayıt eklendi.
+	var changed []Announcement
+	for _, a := range all {
+		if a.ID != first[len(first)-1].ID {
+			changed = append(changed, a)
+		}
+	}
+	changed = append(changed, Announcement{ID: "new", Pinned: true, CreatedAt: "2024-06-01T00:00:00Z"})
+	sortAnnouncements(changed)
+
+	second, _ := fq.page(changed)
+	if want := ids(all[5:10]); ids(second) != want {
+		t.Errorf("second page %s, want %s", ids(second), want)
+	}
+}
+
+func TestFeedCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"", "%%%", "bm90IGpzb24", "e30"} { // e30 = {}
+		if _, ok := decodeFeedCursor(s); ok {
+			t.Errorf("decodeFeedCursor(%q) accepted", s)
+		}
+	}
+}
+
+func TestFoldTR(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"İSTANBUL", "istanbul"},
+		{"istanbul", "istanbul"},
+		{"ıstanbul", "istanbul"},
+		{"ISTANBUL", "istanbul"},
+		{"ŞUBE", "sube"},
+		{"şube", "sube"},
+		{"Çağrı Öğüt", "cagri ogut"},
+		{"Kâğıt", "kagit"},
+	}
+	for _, tt := range tests {
+		if got := foldTR(tt.in); got != tt.want {
+			t.Errorf("foldTR(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	fq := feedQuery{terms: strings.Fields(foldTR("istanbul şube"))}
+	for _, title := range []string{"İSTANBUL ŞUBESİ", "Istanbul subesi", "ıstanbul şubesi"} {
+		if !fq.matches(Announcement{Title: title}) {
+			t.Errorf("search did not match %q", title)
+		}
+	}
+	if fq.matches(Announcement{Title: "Ankara şubesi"}) {
+		t.Error("search matched without every term")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"abc"`
+	lastMod := time.Date(2024, 5, 1, 9, 0, 0, 500_000_000, time.UTC) // saniye altı kısım başlıkta yok
+	at := func(t time.Time) string { return t.Format(http.TimeFormat) }
+	tests := []struct {
+		name string
+		inm  string
+		ims  string
+		last time.Time
+		want bool
+	}{
+		{"no validators", "", "", lastMod, false},
+		{"etag match", `"abc"`, "", lastMod, true},
+		{"etag in list", `"x", "abc"`, "", lastMod, true},
+		{"weak etag", `W/"abc"`, "", lastMod, true},
+		{"star", "*", "", lastMod, true},
+		{"etag mismatch", `"old"`, "", lastMod, false},
+		{"same second", "", at(lastMod), lastMod, true},
+		{"later date", "", at(lastMod.Add(time.Hour)), lastMod, true},
+		{"earlier date", "", at(lastMod.Add(-time.Second)), lastMod, false},
+		{"bad date", "", "dün", lastMod, false},
+		{"no last modified", "", at(lastMod), time.Time{}, false},
+		{"etag mismatch beats current date", `"old"`, at(lastMod), lastMod, false},
+		{"etag match beats stale date", `"abc"`, at(lastMod.Add(-time.Hour)), lastMod, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/announcements", nil)
+			if tt.inm != "" {
+				r.Header.Set("If-None-Match", tt.inm)
+			}
+			if tt.ims != "" {
+				r.Header.Set("If-Modified-Since", tt.ims)
+			}
+			if got := notModified(r, etag, tt.last); got != tt.want {
+				t.Errorf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFeedConditional(t *testing.T) {
+	page := feedFixture()[:3]
+	lastMod := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	get := func(header, value, next string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/announcements?limit=3", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		writeFeed(rec, r, page, next, lastMod)
+		return rec
+	}
+
+	first := get("", "", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Last-Modified") == "" {
+		t.Fatalf("first response %d, ETag %q, Last-Modified %q", first.Code, etag, first.Header().Get("Last-Modified"))
+	}
+
+	again := get("If-None-Match", etag, "")
+	if again.Code != http.StatusNotModified || again.Body.Len() != 0 || again.Header().Get("ETag") != etag {
+		t.Errorf("revalidation: %d, body %d bytes, ETag %q", again.Code, again.Body.Len(), again.Header().Get("ETag"))
+	}
+
+	// Aynı sayfa, farklı sonraki imleç: istemcinin kopyası geçersiz.
+	withNext := get("If-None-Match", etag, "cursor")
+	if withNext.Code != http.StatusOK || withNext.Header().Get("ETag") == etag {
+		t.Errorf("changed cursor: %d, ETag %q", withNext.Code, withNext.Header().Get("ETag"))
+	}
+	if !strings.Contains(withNext.Header().Get("Link"), "cursor=cursor") || withNext.Header().Get("X-Next-Cursor") != "cursor" {
+		t.Errorf("next page headers: Link %q, X-Next-Cursor %q", withNext.Header().Get("Link"), withNext.Header().Get("X-Next-Cursor"))
+	}
+}
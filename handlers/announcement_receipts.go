@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// annReceipt is one person's read/acknowledge state for one announcement.
+type annReceipt struct {
+	ReadAt     string `json:"read_at,omitempty"`
+	AckedAt    string `json:"acked_at,omitempty"`
+	AckVersion string `json:"ack_version,omitempty"` // onaylanan başlık+metin sürümü
+}
+
+// receiptMu guards announcement_receipts.json: duyuru ID -> TC -> kayıt.
+var receiptMu sync.Mutex
+
+func (api *API) receiptFile() string { return api.dataPath("announcement_receipts.json") }
+
+// ackVersion identifies the title and body an acknowledgement applies to.
+// Metin değişirse eski onaylar geçersiz sayılır; sabitleme vb. etkilemez.
+func ackVersion(a Announcement) string {
+	sum := sha256.Sum256([]byte(a.Title + "\x00" + a.Body))
+	return hex.EncodeToString(sum[:6])
+}
+
+// acked reports whether rc acknowledges the current text of a.
+func (rc annReceipt) acked(a Announcement) bool {
+	return rc.AckedAt != "" && rc.AckVersion == ackVersion(a)
+}
+
+// visibleAnnouncement loads {id} if the caller may read it right now.
+// On failure the error response has been written.
+func (api *API) visibleAnnouncement(w http.ResponseWriter, r *http.Request) (Announcement, bool) {
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return Announcement{}, false
+	}
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	if i < 0 || !items[i].live(time.Now()) || !api.newViewer(r).canSee(items[i]) {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return Announcement{}, false
+	}
+	return items[i], true
+}
+
+// POST /api/announcements/{id}/read
+// Çağıranın duyuruyu okuduğunu kaydeder; tekrar çağrılırsa ilk okuma korunur.
+func (api *API) MarkAnnouncementRead(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+
+	rc, err := api.updateReceipt(a.ID, tc, func(rc *annReceipt, now string) {
+		if rc.ReadAt == "" {
+			rc.ReadAt = now
+		}
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	respondJSON(w, http.StatusOK, receiptResponse(a, rc))
+}
+
+type ackInput struct {
+	Accept bool `json:"accept"`
+}
+
+func (in *ackInput) Validate() []apierror.FieldError {
+	if !in.Accept {
+		return []apierror.FieldError{{Field: "accept", Code: "required", Message: "must be true"}}
+	}
+	return nil
+}
+
+// POST /api/announcements/{id}/ack   {"accept": true}
+// "Okudum, kabul ediyorum" onayı. Yalnızca requires_ack duyurularda geçerlidir
+// ve okuma kaydını da oluşturur. Onay denetim kaydına yazılır.
+func (api *API) AckAnnouncement(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	var in ackInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	a, ok := api.visibleAnnouncement(w, r)
+	if !ok {
+		return
+	}
+	if !a.RequiresAck {
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"reason": "announcement does not require acknowledgement"})
+		return
+	}
+
+	version := ackVersion(a)
+	var before annReceipt
+	rc, err := api.updateReceipt(a.ID, tc, func(rc *annReceipt, now string) {
+		before = *rc
+		if rc.ReadAt == "" {
+			rc.ReadAt = now
+		}
+		if rc.AckVersion != version {
+			rc.AckedAt, rc.AckVersion = now, version
+		}
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+	if before.AckVersion != version {
+		api.recordAudit(r, "announcement.ack", a.ID, nil, map[string]any{"acked_at": rc.AckedAt, "version": version})
+	}
+	respondJSON(w, http.StatusOK, receiptResponse(a, rc))
+}
+
+func receiptResponse(a Announcement, rc annReceipt) map[string]any {
+	return map[string]any{
+		"announcement_id": a.ID,
+		"requires_ack":    a.RequiresAck,
+		"read_at":         rc.ReadAt,
+		"acked_at":        rc.AckedAt,
+		"acknowledged":    rc.acked(a),
+	}
+}
+
+// GET /api/announcements/pending-ack
+// Çağıranın henüz (güncel metni) onaylamadığı, onay gerektiren duyurular.
+func (api *API) ListPendingAcks(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	receiptMu.Lock()
+	receipts, err := api.readReceipts()
+	receiptMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+
+	v := api.newViewer(r)
+	now := time.Now()
+	out := make([]Announcement, 0)
+	for _, a := range items {
+		if !a.RequiresAck || a.DeletedAt != "" || a.Archived || !a.live(now) || !v.canSee(a) {
+			continue
+		}
+		if receipts[a.ID][tc].acked(a) {
+			continue
+		}
+		a.History = nil
+		out = append(out, api.present(a, now))
+	}
+	sortAnnouncements(out)
+	respondJSON(w, http.StatusOK, out)
+}
+
+type receiptStaff struct {
+	TC      string `json:"tc"`
+	Ad      string `json:"ad"`
+	Soyad   string `json:"soyad"`
+	Gorev   string `json:"gorev,omitempty"`
+	ReadAt  string `json:"read_at,omitempty"`
+	AckedAt string `json:"acked_at,omitempty"`
+	Status  string `json:"status"` // acknowledged | read | unread
+}
+
+type receiptBranch struct {
+	Sube         string         `json:"sube"`
+	Total        int            `json:"total"`
+	Read         int            `json:"read"`
+	Acknowledged int            `json:"acknowledged"`
+	Pending      int            `json:"pending"`
+	Staff        []receiptStaff `json:"staff"`
+}
+
+// GET /api/announcements/{id}/receipts?sube=&status=pending
+// Duyurunun hedef kitlesindeki personel (Enibra listesi ile eşleştirilerek)
+// şube bazında okundu/onaylandı durumuyla listelenir. requires_ack olan
+// duyurularda "pending" onay vermemişler, diğerlerinde okumamışlardır.
+// Sonuç çağıranın personel görme kapsamıyla sınırlıdır.
+func (api *API) AnnouncementReceipts(w http.ResponseWriter, r *http.Request) {
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	if i < 0 {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	a := items[i]
+
+	qs := r.URL.Query()
+	status := strings.ToLower(strings.TrimSpace(qs.Get("status")))
+	switch status {
+	case "", "pending", "acknowledged", "read", "unread":
+	default:
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"status": "pending | acknowledged | read | unread"})
+		return
+	}
+
+	rows, err := api.enibraPersonnel(r.Context())
+	if err != nil {
+		apierror.Write(w, r, apierror.EnibraUpstream, map[string]any{"cause": err.Error()})
+		return
+	}
+	scope, ok := api.resolveScope(w, r, personnelArea, rows)
+	if !ok {
+		return
+	}
+	receiptMu.Lock()
+	receipts, err := api.readReceipts()
+	receiptMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	byTC := receipts[a.ID]
+
+	now := time.Now()
+	subeFilter := branchKey(qs.Get("sube"))
+	branches := map[string]*receiptBranch{}
+	var total, read, acked, pending int
+	for _, row := range scope.filter(rows) {
+		tc := rowTC(row)
+		if tc == "" {
+			continue
+		}
+		if !a.Audience.empty() && !a.Audience.matches(tc, api.allowRole(tc, now), func() map[string]any { return row }) {
+			continue
+		}
+		sube := rowBranch(row)
+		if subeFilter != "" && branchKey(sube) != subeFilter {
+			continue
+		}
+
+		rc := byTC[tc]
+		st := receiptStaff{
+			TC:      tc,
+			Ad:      strings.TrimSpace(anyToString(firstNonEmpty(row, "ADI", "AD", "ad"))),
+			Soyad:   strings.TrimSpace(anyToString(firstNonEmpty(row, "SOYADI", "SOYAD", "soyad"))),
+			Gorev:   rowGorev(row),
+			ReadAt:  rc.ReadAt,
+			AckedAt: rc.AckedAt,
+			Status:  "unread",
+		}
+		switch {
+		case rc.acked(a):
+			st.Status = "acknowledged"
+		case rc.ReadAt != "":
+			st.Status = "read"
+		}
+		isPending := st.Status == "unread" || (a.RequiresAck && st.Status != "acknowledged")
+
+		key := branchKey(sube)
+		b := branches[key]
+		if b == nil {
+			b = &receiptBranch{Sube: sube, Staff: []receiptStaff{}}
+			branches[key] = b
+		}
+		b.Total++
+		total++
+		if rc.ReadAt != "" {
+			b.Read++
+			read++
+		}
+		if st.Status == "acknowledged" {
+			b.Acknowledged++
+			acked++
+		}
+		if isPending {
+			b.Pending++
+			pending++
+		}
+
+		if status == "" || status == st.Status || (status == "pending" && isPending) {
+			b.Staff = append(b.Staff, st)
+		}
+	}
+
+	out := make([]receiptBranch, 0, len(branches))
+	for _, b := range branches {
+		sort.Slice(b.Staff, func(i, j int) bool {
+			return b.Staff[i].Ad+" "+b.Staff[i].Soyad < b.Staff[j].Ad+" "+b.Staff[j].Soyad
+		})
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Sube < out[j].Sube })
+
+	respondJSON(w, http.StatusOK, map[string]any{
+		"announcement_id": a.ID,
+		"title":           a.Title,
+		"requires_ack":    a.RequiresAck,
+		"scope":           scope.level.String(),
+		"total":           total,
+		"read":            read,
+		"acknowledged":    acked,
+		"pending":         pending,
+		"branches":        out,
+	})
+}
+
+// enibraPersonnel returns the full Enibra personnel list.
+func (api *API) enibraPersonnel(ctx context.Context) ([]map[string]any, error) {
+	cli := api.enibra()
+	if !cli.configured() {
+		return nil, errors.New("enibra not configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, cli.http.Timeout)
+	defer cancel()
+
+	status, body, _, err := cli.personelListesi(ctx, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, errors.New("enibra status " + strconv.Itoa(status))
+	}
+	rows := personnelRows(body)
+	if len(rows) == 0 {
+		return nil, errors.New("enibra returned no personnel")
+	}
+	return rows, nil
+}
+
+// updateReceipt applies fn to the receipt of tc for announcement id and
+// stores it.
+func (api *API) updateReceipt(id, tc string, fn func(rc *annReceipt, now string)) (annReceipt, error) {
+	receiptMu.Lock()
+	defer receiptMu.Unlock()
+
+	all, err := api.readReceipts()
+	if err != nil {
+		return annReceipt{}, err
+	}
+	if all[id] == nil {
+		all[id] = map[string]annReceipt{}
+	}
+	rc := all[id][tc]
+	before := rc
+	fn(&rc, time.Now().UTC().Format(time.RFC3339))
+	if rc == before {
+		return rc, nil
+	}
+	all[id][tc] = rc
+	return rc, api.writeReceipts(all)
+}
+
+func (api *API) readReceipts() (map[string]map[string]annReceipt, error) {
+	b, err := os.ReadFile(api.receiptFile())
+	if os.IsNotExist(err) {
+		return map[string]map[string]annReceipt{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]map[string]annReceipt{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (api *API) writeReceipts(all map[string]map[string]annReceipt) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("announcement_receipts", start, err) }(time.Now())
+
+	b, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	tmp := api.receiptFile() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, api.receiptFile())
+}
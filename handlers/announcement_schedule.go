@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/markdown"
+	"hys-go-backend/metrics"
+)
+
+// annScheduleInterval: publish_at zamanı gelen duyuruların yayınlanma sıklığı.
+const annScheduleInterval = 30 * time.Second
+
+func parseAnnTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+func formatAnnTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// annTimeInput is publish_at or expires_at in an update: absent leaves the
+// field unchanged, null or "" clears it.
+type annTimeInput struct {
+	set bool
+	t   *time.Time
+}
+
+func (in *annTimeInput) UnmarshalJSON(b []byte) error {
+	in.set, in.t = true, nil
+	if s := string(b); s == "null" || s == `""` {
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	in.t = &t
+	return nil
+}
+
+func validateSchedule(publishAt, expiresAt *time.Time) []apierror.FieldError {
+	if expiresAt == nil || expiresAt.IsZero() {
+		return nil
+	}
+	start := time.Now()
+	if publishAt != nil && publishAt.After(start) {
+		start = *publishAt
+	}
+	if !expiresAt.After(start) {
+		return []apierror.FieldError{{Field: "expires_at", Code: "invalid", Message: "must be after publish_at and now"}}
+	}
+	return nil
+}
+
+// due reports whether a non-draft announcement has reached its publish time.
+func (a Announcement) due(now time.Time) bool {
+	if a.Draft {
+		return false
+	}
+	at, ok := parseAnnTime(a.PublishAt)
+	return !ok || !now.Before(at)
+}
+
+func (a Announcement) expired(now time.Time) bool {
+	at, ok := parseAnnTime(a.ExpiresAt)
+	return ok && !now.Before(at)
+}
+
+// live reports whether the announcement belongs in the feed right now.
+// Görünürlük saate göre hesaplanır; zamanlayıcı yalnızca published_at'i
+// işaretleyip bildirimleri tetikler.
+func (a Announcement) live(now time.Time) bool {
+	return a.due(now) && !a.expired(now)
+}
+
+// schedule is shown in the drafts view next to the workflow status.
+func (a Announcement) schedule(now time.Time) string {
+	switch {
+	case a.Draft:
+		return ""
+	case !a.due(now):
+		return "scheduled"
+	case a.expired(now):
+		return "expired"
+	}
+	return "live"
+}
+
+// isEditor: yazar ya da announcement.manage yetkisi olan.
+func (v *viewer) isEditor(a Announcement) bool {
+	return v.manage || (v.tc != "" && a.CreatedBy == v.tc)
+}
+
+type draftView struct {
+	Announcement
+	Schedule string `json:"schedule,omitempty"` // scheduled | expired
+}
+
+// GET /api/announcements/drafts
+// Çağıranın yayında olmayan duyuruları (taslak, incelemede, reddedilmiş,
+// zamanlanmış, süresi dolmuş); yönetici yetkisiyle herkesinkiler.
+func (api *API) ListAnnouncementDrafts(w http.ResponseWriter, r *http.Request) {
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+
+	v := api.newViewer(r)
+	if v.tc == "" && !v.manage {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": AuthHeader + " header required"})
+		return
+	}
+	now := time.Now()
+	out := make([]draftView, 0)
+	for _, a := range items {
+		if a.DeletedAt != "" || a.live(now) || !v.isEditor(a) {
+			continue
+		}
+		a.History = nil
+		out = append(out, draftView{Announcement: api.present(a, now), Schedule: a.schedule(now)})
+	}
+	respondJSON(w, http.StatusOK, out)
+}
+
+var (
+	annHooksMu sync.RWMutex
+	annHooks   []func(Announcement)
+)
+
+// onAnnouncementPublished registers fn to run whenever an announcement
+// becomes visible (on create, on edit, or when the scheduler reaches
+// publish_at). Bildirim kanalları buraya bağlanır.
+func onAnnouncementPublished(fn func(Announcement)) {
+	annHooksMu.Lock()
+	annHooks = append(annHooks, fn)
+	annHooksMu.Unlock()
+}
+
+func announcementPublished(a Announcement) {
+	log.Printf("[INFO] announcement %s published", a.ID)
+	a.BodyText = markdown.Text(a.Body) // bildirim önizlemesi için
+	annHooksMu.RLock()
+	hooks := append([]func(Announcement){}, annHooks...)
+	annHooksMu.RUnlock()
+	for _, fn := range hooks {
+		fn(a)
+	}
+}
+
+// StartAnnouncementScheduler publishes scheduled announcements until ctx is done.
+func (api *API) StartAnnouncementScheduler(ctx context.Context) {
+	RegisterWorker("announcement_scheduler", annScheduleInterval)
+	go func() {
+		t := time.NewTicker(annScheduleInterval)
+		defer t.Stop()
+		for {
+			start := time.Now()
+			n, err := api.publishDueAnnouncements(start)
+			metrics.ObserveJob("announcement_publish", start, err)
+			WorkerHeartbeat("announcement_scheduler")
+			if err != nil {
+				log.Printf("[ERROR] announcement scheduler: %v", err)
+			} else if n > 0 {
+				log.Printf("[INFO] announcement scheduler: %d duyuru yayınlandı", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func (api *API) publishDueAnnouncements(now time.Time) (int, error) {
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	if err != nil {
+		annMu.Unlock()
+		return 0, err
+	}
+	var (
+		published []Announcement
+		changed   bool
+	)
+	for i := range items {
+		a := &items[i]
+		if a.DeletedAt != "" || a.Status != annApproved || !a.due(now) {
+			continue
+		}
+		changed = true
+		if a.publishIfDue(now) {
+			published = append(published, *a)
+		}
+	}
+	if changed {
+		err = api.writeAnnouncements(items)
+	}
+	annMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, a := range published {
+		a.History, a.Workflow = nil, nil
+		api.recordSystemAudit("announcement.publish", a.ID, nil, map[string]any{"published_at": a.PublishedAt})
+		if !a.expired(now) {
+			announcementPublished(a)
+		}
+	}
+	return len(published), nil
+}
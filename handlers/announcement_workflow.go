@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/rbac"
+)
+
+// Duyuru onay akışı:
+//
+//	draft ──submit──▶ pending_review ──approve──▶ approved ──publish_at──▶ published
+//	  ▲                   │     │
+//	  └──────withdraw─────┘     └──reject──▶ rejected ──submit──▶ ...
+//
+// announcement.approve yetkisi olanların ve yalnızca şube(ler)e giden
+// duyuruların incelemesi gerekmez; submit doğrudan approved yapar.
+const (
+	annDraft     = "draft"
+	annPending   = "pending_review"
+	annApproved  = "approved"
+	annRejected  = "rejected"
+	annPublished = "published"
+)
+
+// annTransitions lists the statuses reachable from each status. Her durumdan
+// draft'a dönülebilir: yayındaki duyuru geri çekilir, incelemedeki geri alınır.
+var annTransitions = map[string][]string{
+	annDraft:     {annPending, annApproved},
+	annPending:   {annApproved, annRejected, annDraft},
+	annRejected:  {annDraft, annPending, annApproved},
+	annApproved:  {annPublished, annDraft},
+	annPublished: {annDraft},
+}
+
+var errBadTransition = errors.New("status transition not allowed")
+
+// WorkflowStep is one status change. By is empty for the scheduler.
+type WorkflowStep struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+	By      string `json:"by,omitempty"`
+	At      string `json:"at"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// normalizeStatus fills Status for announcements stored before the workflow.
+func (a *Announcement) normalizeStatus() {
+	if a.Status != "" {
+		return
+	}
+	switch {
+	case a.Draft:
+		a.Status = annDraft
+	case a.PublishedAt != "":
+		a.Status = annPublished
+	default:
+		a.Status = annApproved
+	}
+}
+
+// transition moves a to status to and records the step. Draft follows the
+// status so due/live keep working; approved items are published by
+// publishIfDue.
+func (a *Announcement) transition(to, by, comment string, now time.Time) error {
+	if a.Status != "" && !slices.Contains(annTransitions[a.Status], to) {
+		return errBadTransition
+	}
+	a.Workflow = append(a.Workflow, WorkflowStep{
+		From: a.Status, To: to, By: by, At: now.UTC().Format(time.RFC3339), Comment: comment,
+	})
+	a.Status = to
+	a.Draft = to != annApproved && to != annPublished
+	return nil
+}
+
+// publishIfDue moves an approved announcement whose publish time has come to
+// published. It reports whether this is the first publication; geri çekilip
+// yeniden yayınlanan duyuru için bildirim tekrar gitmez.
+func (a *Announcement) publishIfDue(now time.Time) bool {
+	if a.Status != annApproved || !a.due(now) {
+		return false
+	}
+	_ = a.transition(annPublished, "", "", now)
+	if a.PublishedAt != "" {
+		return false
+	}
+	a.PublishedAt = now.UTC().Format(time.RFC3339)
+	return true
+}
+
+// submit sends a to review, or approves it when no review is needed.
+func (a *Announcement) submit(p *rbac.Policy, role rbac.Role, by string, now time.Time) error {
+	to := annApproved
+	if needsReview(p, *a, role) {
+		to = annPending
+	}
+	return a.transition(to, by, "", now)
+}
+
+// needsReview: onay yetkisi olmayanların şirket geneline ya da tek tek
+// kişilere giden duyuruları incelemeye düşer; yalnızca şube(ler)e
+// gidenler doğrudan onaylanır.
+func needsReview(p *rbac.Policy, a Announcement, role rbac.Role) bool {
+	if p.Allows(role, rbac.AnnouncementApprove) {
+		return false
+	}
+	aud := a.Audience
+	return aud == nil || len(aud.Subeler) == 0 || len(aud.TCs) > 0
+}
+
+func requestRole(r *http.Request) rbac.Role {
+	role, _ := rbac.ParseRole(r.Header.Get("X-Role"))
+	return role
+}
+
+// checkAuthorBranches keeps callers who only hold announcement.create_branch
+// inside their own branches. Şube seçilmemiş duyuru şirket geneli sayılır ve
+// onaya düşer, bu yüzden burada reddedilmez. On failure the error response
+// has been written.
+func (api *API) checkAuthorBranches(w http.ResponseWriter, r *http.Request, aud *AnnouncementAudience) bool {
+	if aud == nil || len(aud.Subeler) == 0 || api.Policy().Allows(requestRole(r), rbac.AnnouncementCreate) {
+		return true
+	}
+	rows, err := api.enibraPersonnel(r.Context())
+	if err != nil {
+		log.Printf("[WARN] announcement author branch lookup: %v", err)
+	}
+	scope, ok := api.resolveScope(w, r, personnelArea, rows)
+	if !ok {
+		return false
+	}
+	if scope.level == scopeCompany {
+		return true
+	}
+	for _, sube := range aud.Subeler {
+		if _, ok := scope.branches[branchKey(sube)]; !ok {
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "branch outside scope", "sube": sube})
+			return false
+		}
+	}
+	return true
+}
+
+// canEditAnnouncement: announcement.manage her duyuruyu, diğerleri yalnızca
+// kendi taslağını ya da reddedilen duyurusunu düzenler.
+func (api *API) canEditAnnouncement(r *http.Request, a Announcement) bool {
+	v := api.newViewer(r)
+	if v.manage {
+		return true
+	}
+	return v.isEditor(a) && (a.Status == annDraft || a.Status == annRejected)
+}
+
+// workflowActions names the audit action for each target status.
+var workflowActions = map[string]string{
+	annDraft:     "announcement.withdraw",
+	annPending:   "announcement.submit",
+	annApproved:  "announcement.approve",
+	annRejected:  "announcement.reject",
+	annPublished: "announcement.publish",
+}
+
+// recordWorkflow audits the steps a went through in this request and
+// notifies the people who have to act on them.
+func (api *API) recordWorkflow(r *http.Request, a Announcement, steps []WorkflowStep) {
+	for _, st := range steps {
+		if st.From == "" && st.To == annDraft {
+			continue // taslak oluşturma announcement.create ile kayıtlı
+		}
+		after := map[string]any{"status": st.To}
+		if st.Comment != "" {
+			after["comment"] = st.Comment
+		}
+		api.recordAudit(r, workflowActions[st.To], a.ID, map[string]any{"status": st.From}, after)
+		api.notifyWorkflow(a, st)
+	}
+}
+
+// workflowNotice tells Recipients (TC) that a reached Step.To.
+type workflowNotice struct {
+	Announcement Announcement
+	Step         WorkflowStep
+	Recipients   []string
+}
+
+var (
+	wfHooksMu sync.RWMutex
+	wfHooks   []func(workflowNotice)
+)
+
+// onWorkflowNotice registers fn for review requests (to approvers) and
+// review decisions (to the author).
+func onWorkflowNotice(fn func(workflowNotice)) {
+	wfHooksMu.Lock()
+	wfHooks = append(wfHooks, fn)
+	wfHooksMu.Unlock()
+}
+
+func (api *API) notifyWorkflow(a Announcement, st WorkflowStep) {
+	var to []string
+	switch st.To {
+	case annPending:
+		to = api.approverTCs()
+		log.Printf("[INFO] announcement %s awaits review, notifying %d approver(s)", a.ID, len(to))
+	case annApproved, annRejected:
+		if st.From != annPending || a.CreatedBy == "" || a.CreatedBy == st.By {
+			return
+		}
+		to = []string{a.CreatedBy}
+		log.Printf("[INFO] announcement %s %s by %s", a.ID, st.To, st.By)
+	default:
+		return
+	}
+	if len(to) == 0 {
+		return
+	}
+	a.History, a.Workflow = nil, nil
+	n := workflowNotice{Announcement: a, Step: st, Recipients: to}
+	wfHooksMu.RLock()
+	hooks := append([]func(workflowNotice){}, wfHooks...)
+	wfHooksMu.RUnlock()
+	for _, fn := range hooks {
+		fn(n)
+	}
+}
+
+// approverTCs lists active allowlist entries whose role may approve.
+func (api *API) approverTCs() []string {
+	api.ensureLoaded()
+	p, now := api.Policy(), time.Now()
+	api.allowDB.RLock()
+	defer api.allowDB.RUnlock()
+	var out []string
+	for tc, it := range api.allowDB.ByTC {
+		if role, ok := rbac.ParseRole(it.Role); ok && it.activeAt(now) && p.Allows(role, rbac.AnnouncementApprove) {
+			out = append(out, tc)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// GET /api/announcements/review-queue -> announcement.approve
+// İnceleme bekleyenler, en eski gönderim önce.
+func (api *API) ListReviewQueue(w http.ResponseWriter, r *http.Request) {
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+
+	now := time.Now()
+	out := make([]Announcement, 0)
+	for _, a := range items {
+		if a.DeletedAt == "" && a.Status == annPending {
+			a.History = nil
+			out = append(out, api.present(a, now))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].submittedAt() < out[j].submittedAt() })
+	respondJSON(w, http.StatusOK, out)
+}
+
+func (a Announcement) submittedAt() string {
+	for i := len(a.Workflow) - 1; i >= 0; i-- {
+		if a.Workflow[i].To == annPending {
+			return a.Workflow[i].At
+		}
+	}
+	return a.CreatedAt
+}
+
+type reviewInput struct {
+	Comment string `json:"comment"`
+
+	requireComment bool
+}
+
+func (in *reviewInput) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	if in.requireComment {
+		errs = requireField(errs, "comment", in.Comment)
+	}
+	return maxLenField(errs, "comment", in.Comment, 1000)
+}
+
+// POST /api/announcements/{id}/submit
+// Yazar ya da announcement.manage; taslak veya reddedilen duyuruyu onaya
+// gönderir (inceleme gerekmiyorsa doğrudan onaylar).
+func (api *API) SubmitAnnouncement(w http.ResponseWriter, r *http.Request) {
+	api.moveAnnouncement(w, r, func(a *Announcement, now time.Time) error {
+		if !api.canEditAnnouncement(r, *a) {
+			return errNotEditor
+		}
+		if a.Status != annDraft && a.Status != annRejected {
+			return errBadTransition
+		}
+		return a.submit(api.Policy(), requestRole(r), actorTC(r), now)
+	})
+}
+
+// POST /api/announcements/{id}/withdraw
+// İncelemedeki duyuruyu yazarı taslağa geri çeker.
+func (api *API) WithdrawAnnouncement(w http.ResponseWriter, r *http.Request) {
+	api.moveAnnouncement(w, r, func(a *Announcement, now time.Time) error {
+		if !api.newViewer(r).isEditor(*a) {
+			return errNotEditor
+		}
+		if a.Status != annPending {
+			return errBadTransition
+		}
+		return a.transition(annDraft, actorTC(r), "", now)
+	})
+}
+
+// POST /api/announcements/{id}/approve {"comment": "..."} -> announcement.approve
+func (api *API) ApproveAnnouncement(w http.ResponseWriter, r *http.Request) {
+	api.reviewAnnouncement(w, r, annApproved)
+}
+
+// POST /api/announcements/{id}/reject {"comment": "..."} -> announcement.approve
+// Ret gerekçesi zorunludur; yazar düzeltip yeniden gönderebilir.
+func (api *API) RejectAnnouncement(w http.ResponseWriter, r *http.Request) {
+	api.reviewAnnouncement(w, r, annRejected)
+}
+
+func (api *API) reviewAnnouncement(w http.ResponseWriter, r *http.Request, to string) {
+	in := reviewInput{requireComment: to == annRejected}
+	// Onayda gövde isteğe bağlı.
+	if (to == annRejected || r.ContentLength != 0) && !decodeJSON(w, r, &in) {
+		return
+	}
+	api.moveAnnouncement(w, r, func(a *Announcement, now time.Time) error {
+		if a.Status != annPending {
+			return errBadTransition
+		}
+		return a.transition(to, actorTC(r), in.Comment, now)
+	})
+}
+
+var errNotEditor = errors.New("not the author")
+
+// moveAnnouncement loads the announcement, applies step under annMu,
+// publishes it when due and writes it back.
+func (api *API) moveAnnouncement(w http.ResponseWriter, r *http.Request, step func(*Announcement, time.Time) error) {
+	annMu.Lock()
+	defer annMu.Unlock()
+
+	items, err := api.readAnnouncements()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	if i < 0 {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+
+	a := &items[i]
+	from, n, now := a.Status, len(a.Workflow), time.Now()
+	switch err := step(a, now); {
+	case errors.Is(err, errNotEditor):
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "only the author can do this"})
+		return
+	case err != nil:
+		apierror.Write(w, r, apierror.Conflict, map[string]any{"status": from, "reason": err.Error()})
+		return
+	}
+	published := a.publishIfDue(now)
+
+	if err := api.writeAnnouncements(items); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+
+	out := items[i]
+	api.recordWorkflow(r, out, out.Workflow[n:])
+	if published {
+		announcementPublished(out)
+	}
+	out.History = nil
+	respondJSON(w, http.StatusOK, api.present(out, now))
+}
@@ -2,104 +2,523 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/markdown"
+	"hys-go-backend/metrics"
+
+	"github.com/gorilla/mux"
 )
 
 type Announcement struct {
 	ID        string `json:"id"`
 	Title     string `json:"title"`
-	Body      string `json:"body"`
+	Body      string `json:"body"` // Markdown kaynağı (bkz. markdown paketi)
 	CreatedAt string `json:"created_at"`
 	CreatedBy string `json:"created_by"`
+
+	Pinned   bool                  `json:"pinned"`
+	Archived bool                  `json:"archived"`
+	Audience *AnnouncementAudience `json:"audience,omitempty"` // nil: herkes
+
+	// RequiresAck: okuyanların "okudum, kabul ediyorum" onayı vermesi gerekir
+	// (bkz. announcement_receipts.go).
+	RequiresAck bool `json:"requires_ack"`
+
+	// Yorum ve tepkiler (bkz. announcement_comments.go).
+	CommentsDisabled bool `json:"comments_disabled"`
+
+	Attachments []Attachment `json:"attachments,omitempty"` // bkz. attachments.go
+
+	// Onay akışı (bkz. announcement_workflow.go). Draft, Status'tan türetilir:
+	// yalnızca approved ve published duyurular yayına girebilir.
+	Status   string         `json:"status"`
+	Workflow []WorkflowStep `json:"workflow,omitempty"`
+
+	// Yayın takvimi (bkz. announcement_schedule.go). Hepsi RFC3339, UTC.
+	Draft       bool   `json:"draft"`
+	PublishAt   string `json:"publish_at,omitempty"`
+	PublishedAt string `json:"published_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+
+	UpdatedAt string `json:"updated_at,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	DeletedAt string `json:"deleted_at,omitempty"` // soft delete: listelerde görünmez
+	DeletedBy string `json:"deleted_by,omitempty"`
+
+	History []AnnouncementRevision `json:"history,omitempty"`
+
+	// Yanıtlarda doldurulur, saklanmaz: Body'nin HTML ve düz metin hali,
+	// yorum ve tepki sayıları.
+	BodyHTML   string      `json:"body_html,omitempty"`
+	BodyText   string      `json:"body_text,omitempty"`
+	Engagement *Engagement `json:"engagement,omitempty"`
 }
 
-var (
-	annFile = filepath.Join("data", "announcements.json")
-	annMu   sync.Mutex
-)
+// AnnouncementRevision is the state of an announcement before one edit.
+type AnnouncementRevision struct {
+	Title    string                `json:"title"`
+	Body     string                `json:"body"`
+	Pinned   bool                  `json:"pinned"`
+	Archived bool                  `json:"archived"`
+	Audience *AnnouncementAudience `json:"audience,omitempty"`
+	EditedAt string                `json:"edited_at"`
+	EditedBy string                `json:"edited_by,omitempty"`
+}
 
-func ListAnnouncements(w http.ResponseWriter, r *http.Request) {
-	annMu.Lock()
-	defer annMu.Unlock()
+var annMu sync.Mutex
 
-	ensureAnnFile()
+func (api *API) annFile() string { return api.dataPath("announcements.json") }
 
-	f, err := os.Open(annFile)
+// GET /api/announcements?q=&archived=true&limit=&cursor=&since=
+// Sabitlenenler en üstte, sonra en yeniler. Arşivdekiler varsayılan akışta
+// yer almaz; q ile arama yapıldığında ya da archived=true verildiğinde gelir.
+// Hedef kitlesi (audience) çağıranı kapsamayan duyurular listelenmez.
+// Taslaklar, yayın zamanı gelmemiş ve süresi dolmuş duyurular da listelenmez.
+//
+// Sayfalama, arama, since= ve ETag/Last-Modified: bkz. announcement_feed.go.
+func (api *API) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	items, err := api.annSnapshot()
 	if err != nil {
-		http.Error(w, "cannot open announcements", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	fq, ok := parseFeedQuery(w, r)
+	if !ok {
+		return
+	}
+	threads, err := api.commentSnapshot()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
 		return
 	}
-	defer f.Close()
 
-	var items []Announcement
-	_ = json.NewDecoder(f).Decode(&items)
+	v := api.newViewer(r)
+	now := time.Now()
+	out := make([]Announcement, 0)
+	for _, a := range items {
+		if fq.hasSince && !a.changedSince(fq.since, now) && !threads[a.ID].changedSince(fq.since) {
+			continue
+		}
+		visible := a.DeletedAt == "" && (!a.Archived || fq.archived) && a.live(now) && v.canSee(a)
+		if !visible {
+			if fq.hasSince {
+				out = append(out, a.tombstone()) // istemci yerel kopyasını silsin
+			}
+			continue
+		}
+		if !fq.matches(a) {
+			continue
+		}
+		out = append(out, a)
+	}
+	sortAnnouncements(out)
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(items)
+	page, next := fq.page(out)
+	for i := range page {
+		if page[i].Title != "" { // tombstone değil
+			page[i].History, page[i].Workflow = nil, nil // geçmiş yalnızca tekil uçta
+			page[i] = api.present(page[i], now)
+			page[i].Engagement = threads[page[i].ID].engagement(v.tc)
+		}
+	}
+	lastMod := feedLastModified(items, now)
+	if t := commentsLastModified(threads); t.After(lastMod) {
+		lastMod = t
+	}
+	writeFeed(w, r, page, next, lastMod)
 }
 
-// POST /api/announcements -> SADECE Patron & IK
-func CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+// GET /api/announcements/{id}   (düzenleme geçmişiyle)
+func (api *API) GetAnnouncement(w http.ResponseWriter, r *http.Request) {
 	annMu.Lock()
-	defer annMu.Unlock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	v := api.newViewer(r)
+	if i < 0 || !v.canSee(items[i]) || !(items[i].live(time.Now()) || v.isEditor(items[i])) {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	a := api.present(items[i], time.Now())
+	if threads, err := api.commentSnapshot(); err == nil {
+		a.Engagement = threads[a.ID].engagement(v.tc)
+	}
+	respondJSON(w, http.StatusOK, a)
+}
 
-	ensureAnnFile()
+type announcementInput struct {
+	Title            string                `json:"title"`
+	Body             string                `json:"body"`
+	Pinned           bool                  `json:"pinned"`
+	Audience         *AnnouncementAudience `json:"audience"`
+	Draft            bool                  `json:"draft"`
+	PublishAt        *time.Time            `json:"publish_at"`
+	ExpiresAt        *time.Time            `json:"expires_at"`
+	RequiresAck      bool                  `json:"requires_ack"`
+	CommentsDisabled bool                  `json:"comments_disabled"`
+	CreatedBy        string                `json:"created_by"` // eski istemciler gönderebilir; yok sayılır
+}
+
+func (in *announcementInput) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	errs = requireField(errs, "title", in.Title)
+	errs = maxLenField(errs, "title", in.Title, 200)
+	errs = requireField(errs, "body", in.Body)
+	errs = maxLenField(errs, "body", in.Body, 10000)
+	errs = markdownField(errs, "body", in.Body)
+	errs = append(errs, in.Audience.normalize()...)
+	errs = append(errs, validateSchedule(in.PublishAt, in.ExpiresAt)...)
+	return errs
+}
 
-	var payload struct {
-		Title     string `json:"title"`
-		Body      string `json:"body"`
-		CreatedBy string `json:"created_by"` // opsiyonel: iOS tarafı gönderebilir
+// POST /api/announcements -> announcement.create ya da announcement.create_branch
+// draft:true taslak olarak saklar; aksi halde duyuru onaya gönderilir
+// (inceleme gerekmiyorsa doğrudan onaylanır, bkz. needsReview).
+func (api *API) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var payload announcementInput
+	if !decodeJSON(w, r, &payload) {
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+	if actorTC(r) == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
 		return
 	}
+	if !api.checkAuthorBranches(w, r, payload.Audience) {
+		return
+	}
+
+	annMu.Lock()
+	defer annMu.Unlock()
 
-	// Var olanları oku
-	f, err := os.Open(annFile)
+	items, err := api.readAnnouncements()
 	if err != nil {
-		http.Error(w, "cannot open announcements", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
 		return
 	}
-	var items []Announcement
-	_ = json.NewDecoder(f).Decode(&items)
-	_ = f.Close()
 
+	createdBy := actorTC(r)
 	ann := Announcement{
-		ID:        time.Now().UTC().Format("20060102150405.000"),
-		Title:     payload.Title,
-		Body:      payload.Body,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		CreatedBy: payload.CreatedBy,
+		ID:               time.Now().UTC().Format("20060102150405.000"),
+		Title:            payload.Title,
+		Body:             payload.Body,
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+		CreatedBy:        createdBy,
+		Pinned:           payload.Pinned,
+		RequiresAck:      payload.RequiresAck,
+		CommentsDisabled: payload.CommentsDisabled,
+		PublishAt:        formatAnnTime(payload.PublishAt),
+		ExpiresAt:        formatAnnTime(payload.ExpiresAt),
+	}
+	if !payload.Audience.empty() {
+		ann.Audience = payload.Audience
 	}
+	now := time.Now()
+	if payload.Draft {
+		_ = ann.transition(annDraft, createdBy, "", now)
+	} else {
+		_ = ann.submit(api.Policy(), requestRole(r), createdBy, now)
+	}
+	published := ann.publishIfDue(now)
 	items = append([]Announcement{ann}, items...) // en üstte görünsün
 
 	// Diske yaz
-	tmp := annFile + ".tmp"
-	out, err := os.Create(tmp)
+	if err := api.writeAnnouncements(items); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+
+	api.recordAudit(r, "announcement.create", ann.ID, nil, ann)
+	api.recordWorkflow(r, ann, ann.Workflow)
+	if published {
+		announcementPublished(ann)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(api.present(ann, time.Now()))
+}
+
+type announcementPut struct {
+	Title            string                `json:"title"`
+	Body             string                `json:"body"`
+	Pinned           bool                  `json:"pinned"`
+	Archived         bool                  `json:"archived"`
+	Audience         *AnnouncementAudience `json:"audience"`
+	Draft            bool                  `json:"draft"`
+	PublishAt        annTimeInput          `json:"publish_at"`
+	ExpiresAt        annTimeInput          `json:"expires_at"`
+	RequiresAck      bool                  `json:"requires_ack"`
+	CommentsDisabled bool                  `json:"comments_disabled"`
+}
+
+func (in *announcementPut) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	errs = requireField(errs, "title", in.Title)
+	errs = maxLenField(errs, "title", in.Title, 200)
+	errs = requireField(errs, "body", in.Body)
+	errs = maxLenField(errs, "body", in.Body, 10000)
+	errs = markdownField(errs, "body", in.Body)
+	errs = append(errs, in.Audience.normalize()...)
+	errs = append(errs, validateSchedule(in.PublishAt.t, in.ExpiresAt.t)...)
+	return errs
+}
+
+// announcementPatch: "audience": {} herkese açar, alan hiç yoksa dokunulmaz.
+// publish_at/expires_at için null ya da "" alanı temizler.
+type announcementPatch struct {
+	Title            *string               `json:"title"`
+	Body             *string               `json:"body"`
+	Pinned           *bool                 `json:"pinned"`
+	Archived         *bool                 `json:"archived"`
+	Audience         *AnnouncementAudience `json:"audience"`
+	Draft            *bool                 `json:"draft"`
+	PublishAt        annTimeInput          `json:"publish_at"`
+	ExpiresAt        annTimeInput          `json:"expires_at"`
+	RequiresAck      *bool                 `json:"requires_ack"`
+	CommentsDisabled *bool                 `json:"comments_disabled"`
+}
+
+func (in *announcementPatch) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	if in.Title != nil {
+		errs = requireField(errs, "title", *in.Title)
+		errs = maxLenField(errs, "title", *in.Title, 200)
+	}
+	if in.Body != nil {
+		errs = requireField(errs, "body", *in.Body)
+		errs = maxLenField(errs, "body", *in.Body, 10000)
+		errs = markdownField(errs, "body", *in.Body)
+	}
+	errs = append(errs, in.Audience.normalize()...)
+	errs = append(errs, validateSchedule(in.PublishAt.t, in.ExpiresAt.t)...)
+	return errs
+}
+
+// PUT /api/announcements/{id}    tüm alanları değiştirir
+// PATCH /api/announcements/{id}  yalnızca gönderilen alanları değiştirir
+// PUT'ta gönderilmeyen publish_at/expires_at temizlenir.
+func (api *API) UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var patch announcementPatch
+	if r.Method == http.MethodPut {
+		var put announcementPut
+		if !decodeJSON(w, r, &put) {
+			return
+		}
+		if put.Audience == nil {
+			put.Audience = &AnnouncementAudience{}
+		}
+		patch = announcementPatch{
+			Title: &put.Title, Body: &put.Body, Pinned: &put.Pinned, Archived: &put.Archived,
+			Audience: put.Audience, Draft: &put.Draft,
+			PublishAt: annTimeInput{set: true, t: put.PublishAt.t}, ExpiresAt: annTimeInput{set: true, t: put.ExpiresAt.t},
+			RequiresAck: &put.RequiresAck, CommentsDisabled: &put.CommentsDisabled,
+		}
+	} else if !decodeJSON(w, r, &patch) {
+		return
+	}
+	if !api.checkAuthorBranches(w, r, patch.Audience) {
+		return
+	}
+
+	annMu.Lock()
+	defer annMu.Unlock()
+
+	items, err := api.readAnnouncements()
 	if err != nil {
-		http.Error(w, "cannot persist", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
 		return
 	}
-	if err := json.NewEncoder(out).Encode(items); err != nil {
-		out.Close()
-		http.Error(w, "cannot persist", http.StatusInternalServerError)
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	if i < 0 {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	if !api.canEditAnnouncement(r, items[i]) {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "only own drafts and rejected announcements can be edited"})
 		return
 	}
-	out.Close()
-	_ = os.Rename(tmp, annFile)
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(ann)
+	before := items[i]
+	a := &items[i]
+	n := len(a.Workflow)
+	now := time.Now().UTC().Format(time.RFC3339)
+	actor := actorTC(r)
+	a.History = append(a.History, AnnouncementRevision{
+		Title: a.Title, Body: a.Body, Pinned: a.Pinned, Archived: a.Archived, Audience: a.Audience,
+		EditedAt: now, EditedBy: actor,
+	})
+	if patch.Title != nil {
+		a.Title = *patch.Title
+	}
+	if patch.Body != nil {
+		a.Body = *patch.Body
+	}
+	if patch.Pinned != nil {
+		a.Pinned = *patch.Pinned
+	}
+	if patch.Archived != nil {
+		a.Archived = *patch.Archived
+	}
+	if patch.Audience != nil {
+		a.Audience = patch.Audience
+		if a.Audience.empty() {
+			a.Audience = nil
+		}
+	}
+	if patch.RequiresAck != nil {
+		a.RequiresAck = *patch.RequiresAck
+	}
+	if patch.CommentsDisabled != nil {
+		a.CommentsDisabled = *patch.CommentsDisabled
+	}
+	if patch.PublishAt.set {
+		a.PublishAt = formatAnnTime(patch.PublishAt.t)
+	}
+	if patch.ExpiresAt.set {
+		a.ExpiresAt = formatAnnTime(patch.ExpiresAt.t)
+	}
+	// draft:true yayından/incelemeden geri çeker, draft:false taslağı onaya
+	// gönderir. Onaylanmış duyurunun düzenlenmesi yeniden onaya düşmez.
+	if patch.Draft != nil {
+		switch {
+		case *patch.Draft && a.Status != annDraft:
+			_ = a.transition(annDraft, actor, "", time.Now())
+		case !*patch.Draft && (a.Status == annDraft || a.Status == annRejected):
+			_ = a.submit(api.Policy(), requestRole(r), actor, time.Now())
+		}
+	}
+	publishNow := a.publishIfDue(time.Now())
+	a.UpdatedAt, a.UpdatedBy = now, actor
+
+	if err := api.writeAnnouncements(items); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+
+	before.History, items[i].History = nil, nil
+	after := items[i]
+	before.Workflow, after.Workflow = nil, nil
+	api.recordAudit(r, "announcement.update", a.ID, before, after)
+	api.recordWorkflow(r, items[i], items[i].Workflow[n:])
+	if publishNow {
+		announcementPublished(items[i])
+	}
+	respondJSON(w, http.StatusOK, api.present(items[i], time.Now()))
+}
+
+// DELETE /api/announcements/{id}
+// Kayıt silinmez, deleted_at ile işaretlenir; geçmiş ve denetim korunur.
+func (api *API) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	annMu.Lock()
+	defer annMu.Unlock()
+
+	items, err := api.readAnnouncements()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, mux.Vars(r)["id"])
+	if i < 0 {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+
+	items[i].DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	items[i].DeletedBy = actorTC(r)
+	if err := api.writeAnnouncements(items); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+
+	api.recordAudit(r, "announcement.delete", items[i].ID, map[string]any{"title": items[i].Title}, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// present prepares a for a response: sanitized HTML and plain-text
+// renderings of the body, and signed attachment links.
+func (api *API) present(a Announcement, now time.Time) Announcement {
+	a.BodyHTML, a.BodyText = markdown.HTML(a.Body), markdown.Text(a.Body)
+	return api.withAttachmentURLs(a, now)
+}
+
+// findAnnouncement returns the index of a non-deleted announcement, or -1.
+func findAnnouncement(items []Announcement, id string) int {
+	for i := range items {
+		if items[i].ID == id && items[i].DeletedAt == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortAnnouncements keeps pinned items first, each group newest first.
+func sortAnnouncements(items []Announcement) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Pinned != items[j].Pinned {
+			return items[i].Pinned
+		}
+		if items[i].CreatedAt != items[j].CreatedAt {
+			return items[i].CreatedAt > items[j].CreatedAt
+		}
+		return items[i].ID > items[j].ID // imleç için kesin sıra
+	})
+}
+
+// readAnnouncements loads the whole file. Caller holds annMu.
+func (api *API) readAnnouncements() ([]Announcement, error) {
+	api.ensureAnnFile()
+
+	f, err := os.Open(api.annFile())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Bozuk dosya boş liste sayılmaz: sonraki yazma tüm duyuruları silerdi.
+	var items []Announcement
+	if err := json.NewDecoder(f).Decode(&items); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", api.annFile(), err)
+	}
+	for i := range items {
+		items[i].normalizeStatus()
+	}
+	return items, nil
+}
+
+func (api *API) writeAnnouncements(items []Announcement) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("announcements", start, err) }(time.Now())
+	defer annCacheGen.Add(1)
+
+	tmp := api.annFile() + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(out).Encode(items); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, api.annFile())
 }
 
-func ensureAnnFile() {
-	_ = os.MkdirAll(filepath.Dir(annFile), 0755)
-	if _, err := os.Stat(annFile); os.IsNotExist(err) {
-		_ = os.WriteFile(annFile, []byte("[]"), 0644)
+func (api *API) ensureAnnFile() {
+	_ = os.MkdirAll(filepath.Dir(api.annFile()), 0755)
+	if _, err := os.Stat(api.annFile()); os.IsNotExist(err) {
+		_ = os.WriteFile(api.annFile(), []byte("[]"), 0644)
 	}
 }
@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // image.Decode için
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/metrics"
+	"hys-go-backend/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAttachments   = 20      // duyuru başına
+	maxThumbPixels   = 50e6    // daha büyük görsellerin küçüğü üretilmez
+	thumbMaxSide     = 320     // px
+	maxAttachmentRaw = 1 << 20 // multipart başlıkları ve form alanları için pay
+)
+
+// Attachment is a file stored in the blob store and listed on its
+// announcement. URL fields are signed per response and never persisted.
+type Attachment struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ContentType  string `json:"content_type"` // içerikten tespit edilen tür
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	HasThumbnail bool   `json:"has_thumbnail"`
+	UploadedAt   string `json:"uploaded_at"`
+	UploadedBy   string `json:"uploaded_by,omitempty"`
+
+	URL          string `json:"url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	URLExpiresAt string `json:"url_expires_at,omitempty"`
+}
+
+// attachmentTypes are the sniffed types we accept. Office files sniff as zip
+// and are told apart by extension.
+var (
+	attachmentTypes = map[string]bool{
+		"application/pdf": true,
+		"image/png":       true,
+		"image/jpeg":      true,
+		"image/gif":       true,
+		"image/webp":      true,
+	}
+	zipTypes = map[string]string{
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	}
+)
+
+// sniffAttachment returns the stored content type for data, ignoring what
+// the client declared.
+func sniffAttachment(data []byte, name string) (string, bool) {
+	ct, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case attachmentTypes[ct]:
+		return ct, true
+	case ct == "application/zip" && zipTypes[ext] != "":
+		return zipTypes[ext], true
+	case ct == "text/plain" && ext == ".csv":
+		return "text/csv; charset=utf-8", true
+	}
+	return ct, false
+}
+
+// POST /api/announcements/{id}/attachments   (multipart/form-data, "file" alanları)
+// Her dosya ATTACHMENT_MAX_MB ile sınırlıdır; türü içerikten tespit edilir.
+// Görseller için küçük bir JPEG önizleme üretilir.
+func (api *API) UploadAttachments(w http.ResponseWriter, r *http.Request) {
+	bs := api.blobs()
+	if bs == nil {
+		apierror.Write(w, r, apierror.ServerNotConfigured, map[string]any{"reason": "blob store unavailable"})
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "multipart/form-data" {
+		apierror.Write(w, r, apierror.UnsupportedMedia, map[string]any{"content_type": r.Header.Get("Content-Type"), "expected": "multipart/form-data"})
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, id)
+	if i < 0 {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	room := maxAttachments - len(items[i].Attachments)
+
+	limit := api.currentConfig().Blob.MaxUploadBytes
+	r.Body = http.MaxBytesReader(w, r.Body, limit*int64(maxAttachments)+maxAttachmentRaw)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"reason": err.Error()})
+		return
+	}
+
+	var added []Attachment
+	fail := func(code apierror.Code, details map[string]any) {
+		removeAttachmentBlobs(r.Context(), bs, id, added)
+		apierror.Write(w, r, code, details)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(uploadErrorCode(err))
+			return
+		}
+		name := cleanFileName(part.FileName())
+		if part.FormName() != "file" || name == "" {
+			part.Close()
+			continue
+		}
+		if len(added) >= room {
+			fail(apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+				Field: "file", Code: "too_many", Message: "at most " + strconv.Itoa(maxAttachments) + " attachments per announcement",
+			}}})
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, limit+1))
+		part.Close()
+		if err != nil {
+			fail(uploadErrorCode(err))
+			return
+		}
+		if int64(len(data)) > limit {
+			fail(apierror.BodyTooLarge, map[string]any{"file": name, "limit_bytes": limit})
+			return
+		}
+		if len(data) == 0 {
+			fail(apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{Field: "file", Code: "empty", Message: name}}})
+			return
+		}
+		ct, ok := sniffAttachment(data, name)
+		if !ok {
+			fail(apierror.UnsupportedFile, map[string]any{"file": name, "detected": ct})
+			return
+		}
+
+		sum := sha256.Sum256(data)
+		att := Attachment{
+			ID:          newShortID(),
+			Name:        name,
+			ContentType: ct,
+			Size:        int64(len(data)),
+			SHA256:      hex.EncodeToString(sum[:]),
+			UploadedAt:  now,
+			UploadedBy:  actorTC(r),
+		}
+		if err := putBlob(r.Context(), bs, attachmentKey(id, att.ID, false), data, ct); err != nil {
+			log.Printf("[ERROR] attachment upload %s: %v", id, err)
+			fail(apierror.StoreWriteFailed, nil)
+			return
+		}
+		added = append(added, att)
+
+		if strings.HasPrefix(ct, "image/") {
+			if thumb, width, height, ok := makeThumbnail(data); ok {
+				added[len(added)-1].Width, added[len(added)-1].Height = width, height
+				if err := putBlob(r.Context(), bs, attachmentKey(id, att.ID, true), thumb, "image/jpeg"); err != nil {
+					log.Printf("[WARN] attachment thumbnail %s/%s: %v", id, att.ID, err)
+				} else {
+					added[len(added)-1].HasThumbnail = true
+				}
+			}
+		}
+	}
+	if len(added) == 0 {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{Field: "file", Code: "required"}}})
+		return
+	}
+
+	annMu.Lock()
+	items, err = api.readAnnouncements()
+	if err == nil {
+		if i = findAnnouncement(items, id); i >= 0 && len(items[i].Attachments)+len(added) <= maxAttachments {
+			items[i].Attachments = append(items[i].Attachments, added...)
+			items[i].UpdatedAt, items[i].UpdatedBy = now, actorTC(r)
+			err = api.writeAnnouncements(items)
+		}
+	}
+	annMu.Unlock()
+	switch {
+	case err != nil:
+		fail(apierror.StoreWriteFailed, nil)
+		return
+	case i < 0:
+		fail(apierror.NotFound, nil)
+		return
+	case !containsAttachment(items[i].Attachments, added[0].ID): // bu arada başka yükleme sınırı doldurdu
+		fail(apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{Field: "file", Code: "too_many"}}})
+		return
+	}
+
+	api.recordAudit(r, "announcement.attachment.add", id, nil, added)
+	respondJSON(w, http.StatusCreated, api.signAttachments(id, added, time.Now()))
+}
+
+// DELETE /api/announcements/{id}/attachments/{att}
+func (api *API) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	if err != nil {
+		annMu.Unlock()
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, vars["id"])
+	j := -1
+	if i >= 0 {
+		j = attachmentIndex(items[i].Attachments, vars["att"])
+	}
+	if j < 0 {
+		annMu.Unlock()
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	att := items[i].Attachments[j]
+	items[i].Attachments = append(items[i].Attachments[:j:j], items[i].Attachments[j+1:]...)
+	items[i].UpdatedAt, items[i].UpdatedBy = time.Now().UTC().Format(time.RFC3339), actorTC(r)
+	err = api.writeAnnouncements(items)
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+		return
+	}
+
+	if bs := api.blobs(); bs != nil {
+		removeAttachmentBlobs(r.Context(), bs, vars["id"], []Attachment{att})
+	}
+	api.recordAudit(r, "announcement.attachment.delete", vars["id"], att, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/announcements/{id}/attachments/{att}
+// GET /api/announcements/{id}/attachments/{att}/thumbnail
+// İmzalı bağlantı (exp, sig) ile ya da duyuruyu görebilen oturumla indirilir.
+// download=1 tarayıcıda açmak yerine indirmeye zorlar.
+func (api *API) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	thumb := strings.HasSuffix(r.URL.Path, "/thumbnail")
+
+	annMu.Lock()
+	items, err := api.readAnnouncements()
+	annMu.Unlock()
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	i := findAnnouncement(items, vars["id"])
+	j := -1
+	if i >= 0 {
+		j = attachmentIndex(items[i].Attachments, vars["att"])
+	}
+	if j < 0 || (thumb && !items[i].Attachments[j].HasThumbnail) {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	a, att := items[i], items[i].Attachments[j]
+
+	q := r.URL.Query()
+	if q.Get("sig") != "" {
+		if !api.validAttachmentSig(a.ID, att.ID, thumb, q.Get("exp"), q.Get("sig"), time.Now()) {
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "invalid or expired link"})
+			return
+		}
+	} else {
+		v := api.newViewer(r)
+		if v.tc == "" && !v.manage {
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": AuthHeader + " header or signed link required"})
+			return
+		}
+		if !v.canSee(a) || !(a.live(time.Now()) || v.isEditor(a)) {
+			apierror.Write(w, r, apierror.NotFound, nil)
+			return
+		}
+	}
+
+	bs := api.blobs()
+	if bs == nil {
+		apierror.Write(w, r, apierror.ServerNotConfigured, map[string]any{"reason": "blob store unavailable"})
+		return
+	}
+	rc, size, err := bs.Open(r.Context(), attachmentKey(a.ID, att.ID, thumb))
+	if errors.Is(err, storage.ErrNotFound) {
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] attachment download %s/%s: %v", a.ID, att.ID, err)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	defer rc.Close()
+
+	ct, name := att.ContentType, att.Name
+	if thumb {
+		ct, name = "image/jpeg", strings.TrimSuffix(name, filepath.Ext(name))+"_thumb.jpg"
+	}
+	disposition := "attachment"
+	if download, _ := strconv.ParseBool(q.Get("download")); !download && (strings.HasPrefix(ct, "image/") || ct == "application/pdf") {
+		disposition = "inline"
+	}
+	h := w.Header()
+	h.Set("Content-Type", ct)
+	h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, max-age=300")
+	if !thumb {
+		h.Set("ETag", `"`+att.SHA256+`"`)
+	}
+	if size > 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, rc)
+	}
+}
+
+// withAttachmentURLs returns a with freshly signed download links. The
+// attachment slice is copied so the stored records stay untouched.
+func (api *API) withAttachmentURLs(a Announcement, now time.Time) Announcement {
+	if len(a.Attachments) > 0 {
+		a.Attachments = api.signAttachments(a.ID, a.Attachments, now)
+	}
+	return a
+}
+
+func (api *API) signAttachments(annID string, atts []Attachment, now time.Time) []Attachment {
+	// Süre TTL/2'lik pencerelere yuvarlanır: aynı pencerede üretilen
+	// bağlantılar aynı kalır ve duyuru akışının ETag'i değişmez.
+	ttl := api.currentConfig().Blob.URLTTL
+	exp := now.Truncate(ttl / 2).Add(ttl).Unix()
+	out := make([]Attachment, len(atts))
+	for i, att := range atts {
+		att.URL = api.attachmentURL(annID, att.ID, false, exp)
+		if att.HasThumbnail {
+			att.ThumbnailURL = api.attachmentURL(annID, att.ID, true, exp)
+		}
+		att.URLExpiresAt = time.Unix(exp, 0).UTC().Format(time.RFC3339)
+		out[i] = att
+	}
+	return out
+}
+
+func (api *API) attachmentURL(annID, attID string, thumb bool, exp int64) string {
+	p := "/api/announcements/" + url.PathEscape(annID) + "/attachments/" + url.PathEscape(attID)
+	if thumb {
+		p += "/thumbnail"
+	}
+	e := strconv.FormatInt(exp, 10)
+	return p + "?exp=" + e + "&sig=" + api.attachmentSig(annID, attID, thumb, e)
+}
+
+func (api *API) attachmentSig(annID, attID string, thumb bool, exp string) string {
+	m := hmac.New(sha256.New, api.attachmentSecret())
+	m.Write([]byte(annID + "\n" + attID + "\n" + strconv.FormatBool(thumb) + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (api *API) validAttachmentSig(annID, attID string, thumb bool, exp, sig string, now time.Time) bool {
+	n, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > n {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(api.attachmentSig(annID, attID, thumb, exp)))
+}
+
+var (
+	fallbackSecretOnce sync.Once
+	fallbackSecret     []byte
+)
+
+// attachmentSecret is ATTACHMENT_URL_SECRET, or a per-process random key
+// (bağlantılar yeniden başlatmada geçersiz olur).
+func (api *API) attachmentSecret() []byte {
+	if s := api.currentConfig().Blob.URLSecret; s != "" {
+		return []byte(s)
+	}
+	fallbackSecretOnce.Do(func() {
+		fallbackSecret = make([]byte, 32)
+		_, _ = rand.Read(fallbackSecret)
+	})
+	return fallbackSecret
+}
+
+func attachmentKey(annID, attID string, thumb bool) string {
+	key := "announcements/" + annID + "/" + attID
+	if thumb {
+		key += ".thumb.jpg"
+	}
+	return key
+}
+
+func putBlob(ctx context.Context, bs storage.BlobStore, key string, data []byte, ct string) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("blobs", start, err) }(time.Now())
+	return bs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), ct)
+}
+
+func removeAttachmentBlobs(ctx context.Context, bs storage.BlobStore, annID string, atts []Attachment) {
+	for _, att := range atts {
+		for _, thumb := range []bool{false, true} {
+			if thumb && !att.HasThumbnail {
+				continue
+			}
+			if err := bs.Delete(ctx, attachmentKey(annID, att.ID, thumb)); err != nil {
+				log.Printf("[WARN] attachment cleanup %s/%s: %v", annID, att.ID, err)
+			}
+		}
+	}
+}
+
+func uploadErrorCode(err error) (apierror.Code, map[string]any) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return apierror.BodyTooLarge, map[string]any{"limit_bytes": maxErr.Limit}
+	}
+	return apierror.InvalidRequest, map[string]any{"reason": "malformed multipart body"}
+}
+
+func attachmentIndex(atts []Attachment, id string) int {
+	for i := range atts {
+		if atts[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsAttachment(atts []Attachment, id string) bool { return attachmentIndex(atts, id) >= 0 }
+
+func newShortID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cleanFileName keeps the base name without path or control characters.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if rs := []rune(name); len(rs) > 200 {
+		name = string(rs[len(rs)-200:])
+	}
+	return name
+}
+
+// makeThumbnail decodes an image and returns a JPEG whose longer side is at
+// most thumbMaxSide, plus the original dimensions. WebP decoding is not in
+// the standard library, so WebP files get no thumbnail.
+func makeThumbnail(data []byte) ([]byte, int, int, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || float64(cfg.Width)*float64(cfg.Height) > maxThumbPixels {
+		return nil, 0, 0, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, false
+	}
+
+	sw, sh := cfg.Width, cfg.Height
+	tw, th := sw, sh
+	if sw > thumbMaxSide || sh > thumbMaxSide {
+		if sw >= sh {
+			tw, th = thumbMaxSide, max(1, sh*thumbMaxSide/sw)
+		} else {
+			tw, th = max(1, sw*thumbMaxSide/sh), thumbMaxSide
+		}
+	}
+
+	// Kutu filtresi: her hedef pikseli kapsadığı kaynak piksellerin ortalaması.
+	// Saydam alanlar beyaz zemine oturtulur (JPEG alfa taşımaz).
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*sh/th, max((y+1)*sh/th, y*sh/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*sw/tw, max((x+1)*sw/tw, x*sw/tw+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					white := 0xffff - uint64(ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, false
+	}
+	return buf.Bytes(), sw, sh, true
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/rbac"
+)
+
+// AnnouncementAudience limits who sees an announcement. A nil audience
+// means everyone. TCs listed explicitly always see it; otherwise every
+// non-empty list must contain the viewer's value (listeler arası VE,
+// liste içi VEYA).
+type AnnouncementAudience struct {
+	Subeler   []string `json:"subeler,omitempty"`
+	KonumTipi []string `json:"konum_tipi,omitempty"` // GENEL_MERKEZ | MAGAZA
+	Gorevler  []string `json:"gorevler,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	TCs       []string `json:"tcs,omitempty"`
+}
+
+var konumTipleri = map[string]bool{"GENEL_MERKEZ": true, "MAGAZA": true}
+
+// normalize cleans the lists in place and reports invalid values.
+func (a *AnnouncementAudience) normalize() []apierror.FieldError {
+	if a == nil {
+		return nil
+	}
+	var errs []apierror.FieldError
+	for i, k := range a.KonumTipi {
+		k = strings.ToUpper(strings.TrimSpace(k))
+		if !konumTipleri[k] {
+			errs = append(errs, apierror.FieldError{Field: "audience.konum_tipi", Code: "invalid", Message: "GENEL_MERKEZ | MAGAZA"})
+		}
+		a.KonumTipi[i] = k
+	}
+	for i, name := range a.Roles {
+		role, ok := rbac.ParseRole(name)
+		if !ok {
+			errs = append(errs, apierror.FieldError{Field: "audience.roles", Code: "invalid", Message: roleChoices()})
+		}
+		a.Roles[i] = string(role)
+	}
+	for i, tc := range a.TCs {
+		tc = identity.Normalize(tc)
+		if err := identity.Validate(tc); err != nil {
+			errs = append(errs, apierror.FieldError{Field: "audience.tcs", Code: "invalid", Message: err.Error()})
+		}
+		a.TCs[i] = tc
+	}
+	return errs
+}
+
+func (a *AnnouncementAudience) empty() bool {
+	return a == nil || len(a.Subeler)+len(a.KonumTipi)+len(a.Gorevler)+len(a.Roles)+len(a.TCs) == 0
+}
+
+// needsRecord reports whether matching needs the viewer's Enibra record.
+func (a *AnnouncementAudience) needsRecord() bool {
+	return len(a.Subeler)+len(a.KonumTipi)+len(a.Gorevler) > 0
+}
+
+// viewer is the caller as seen by audience rules. The Enibra record is
+// fetched at most once and only when an announcement needs it.
+type viewer struct {
+	api    *API
+	r      *http.Request
+	tc     string
+	role   string
+	manage bool
+
+	once   sync.Once
+	record map[string]any
+}
+
+func (api *API) newViewer(r *http.Request) *viewer { return api.viewerFor(r, actorTC(r)) }
+
+// viewerFor builds the viewer for tc. Rol X-Role'den değil allowlist'ten
+// gelir; TC'siz çağıran sıradan personel sayılır.
+func (api *API) viewerFor(r *http.Request, tc string) *viewer {
+	v := &viewer{api: api, r: r, tc: tc, role: string(rbac.Personel)}
+	if tc != "" {
+		v.role = api.allowRole(tc, time.Now())
+	}
+	if role, ok := rbac.ParseRole(v.role); ok {
+		v.manage = api.Policy().Allows(role, rbac.AnnouncementManage)
+	}
+	return v
+}
+
+func (v *viewer) enibraRecord() map[string]any {
+	v.once.Do(func() {
+		if v.tc == "" {
+			return
+		}
+		cli := v.api.enibra()
+		if !cli.configured() {
+			return
+		}
+		ctx, cancel := context.WithTimeout(v.r.Context(), cli.http.Timeout)
+		defer cancel()
+		status, body, _, err := cli.personelListesi(ctx, url.Values{})
+		if err != nil || status < 200 || status >= 300 {
+			return // hedefli duyurular gösterilmez (fail closed)
+		}
+		for _, row := range personnelRows(body) {
+			if rowTC(row) == v.tc {
+				v.record = row
+				return
+			}
+		}
+	})
+	return v.record
+}
+
+// canSee reports whether the viewer is in a's audience. Authors and anyone
+// who can manage announcements see everything.
+func (v *viewer) canSee(a Announcement) bool {
+	if a.Audience.empty() || v.manage || (v.tc != "" && a.CreatedBy == v.tc) {
+		return true
+	}
+	return a.Audience.matches(v.tc, v.role, v.enibraRecord)
+}
+
+// matches reports whether the person with tc, role and Enibra record is in
+// a non-empty audience. record is only called when a list needs it.
+func (aud *AnnouncementAudience) matches(tc, role string, record func() map[string]any) bool {
+	if tc != "" && containsFold(aud.TCs, tc) {
+		return true
+	}
+	if len(aud.Subeler)+len(aud.KonumTipi)+len(aud.Gorevler)+len(aud.Roles) == 0 {
+		return false // yalnızca kişilere gönderilmiş
+	}
+	if len(aud.Roles) > 0 && !containsFold(aud.Roles, role) {
+		return false
+	}
+	if !aud.needsRecord() {
+		return true
+	}
+	row := record()
+	if row == nil {
+		return false
+	}
+	if len(aud.Subeler) > 0 && !containsBranch(aud.Subeler, rowBranch(row)) {
+		return false
+	}
+	if len(aud.KonumTipi) > 0 && !containsFold(aud.KonumTipi, konumTipi(row)) {
+		return false
+	}
+	if len(aud.Gorevler) > 0 && !containsBranch(aud.Gorevler, rowGorev(row)) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsBranch compares with branchKey so Turkish spelling differences match.
+func containsBranch(list []string, v string) bool {
+	k := branchKey(v)
+	if k == "" {
+		return false
+	}
+	for _, s := range list {
+		if branchKey(s) == k {
+			return true
+		}
+	}
+	return false
+}
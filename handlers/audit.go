@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/audit"
+	"hys-go-backend/identity"
+	"hys-go-backend/middlewares"
+	"hys-go-backend/reqid"
+)
+
+// ActorHeader carries the TC of the signed-in user; X-Role carries the role.
+// Both are set by middleware (Authenticate, AllowlistRoles), never trusted
+// from the client.
+const ActorHeader = "X-TC"
+
+// auditTrail opens data/audit.log on first use. DataDir değişikliği restart
+// gerektirdiği için dosya yolu süreç boyunca sabit.
+func (api *API) auditTrail() *audit.Log {
+	api.auditOnce.Do(func() {
+		l, err := audit.Open(api.dataPath("audit.log"))
+		if err != nil {
+			log.Printf("[ERROR] audit log: %v", err)
+		}
+		api.auditLog = l
+	})
+	return api.auditLog
+}
+
+// actorTC returns the normalized caller TC, or "" when absent or invalid.
+func actorTC(r *http.Request) string {
+	tc := identity.Normalize(r.Header.Get(ActorHeader))
+	if identity.Validate(tc) != nil {
+		return ""
+	}
+	return tc
+}
+
+// recordAudit appends one entry for the current request. Failures are
+// logged; the action itself has already happened and is not rolled back.
+func (api *API) recordAudit(r *http.Request, action, target string, before, after any) {
+	api.appendAudit(audit.Entry{
+		ActorTC:   actorTC(r),
+		ActorRole: strings.TrimSpace(r.Header.Get("X-Role")),
+		IP:        middlewares.ClientIP(r, api.currentConfig().Limits.TrustProxyHeaders),
+		RequestID: reqid.From(r.Context()),
+		Action:    action,
+		Target:    target,
+		Before:    rawJSON(before),
+		After:     rawJSON(after),
+	})
+}
+
+func (api *API) appendAudit(e audit.Entry) {
+	l := api.auditTrail()
+	if l == nil {
+		return
+	}
+	if err := l.Append(e); err != nil {
+		log.Printf("[ERROR] audit %s %s rid=%s: %v", e.Action, identity.Redact(e.Target), e.RequestID, err)
+	}
+}
+
+func rawJSON(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// GET /api/admin/audit?action=&actor=&target=&since=&until=&limit=
+// since/until: RFC3339. limit varsayılan 100, en fazla 1000.
+func (api *API) ListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Action:  strings.TrimSpace(q.Get("action")),
+		ActorTC: identity.Normalize(q.Get("actor")),
+		Target:  strings.TrimSpace(q.Get("target")),
+		Limit:   100,
+	}
+	var errs []apierror.FieldError
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, apierror.FieldError{Field: p.name, Code: "invalid", Message: "RFC3339"})
+				continue
+			}
+			*p.dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			errs = append(errs, apierror.FieldError{Field: "limit", Code: "invalid", Message: "1-1000"})
+		} else {
+			f.Limit = n
+		}
+	}
+	if len(errs) > 0 {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": errs})
+		return
+	}
+
+	l := api.auditTrail()
+	if l == nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	entries, err := l.Query(f)
+	if err != nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// GET /api/admin/audit/verify
+// Zinciri baştan sona doğrular; ilk bozuk kaydı bildirir.
+func (api *API) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	l := api.auditTrail()
+	if l == nil {
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+		return
+	}
+	n, err := l.Verify()
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "entries": n})
+	case errors.Is(err, audit.ErrBroken):
+		writeJSON(w, http.StatusOK, map[string]any{"ok": false, "entries": n, "error": err.Error()})
+	default:
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"hys-go-backend/audit"
+	"hys-go-backend/config"
+	"hys-go-backend/rbac"
+	"hys-go-backend/storage"
+)
+
+// API holds the configuration and the state derived from it. Handlers are
+// its methods; main builds one with New and hands it to the router, so
+// nothing reads the configuration from package state.
+type API struct {
+	activeConfig atomic.Pointer[config.Config]
+	activeEnibra atomic.Pointer[enibraClient]
+	activePolicy atomic.Pointer[rbac.Policy]
+	activeBlobs  atomic.Pointer[storage.BlobStore]
+
+	allowDB         *allowStore
+	onceLoadAllowDB sync.Once
+	allowLoadErr    error // readiness için: ilk yükleme hatası
+
+	auditOnce sync.Once
+	auditLog  *audit.Log
+
+	// streams ends every live stream; StopStreams cancels it on shutdown.
+	streams     context.Context
+	stopStreams context.CancelFunc
+}
+
+// New builds the handler set for cfg.
+func New(cfg *config.Config) *API {
+	api := &API{allowDB: &allowStore{ByTC: map[string]allowItem{}}}
+	api.streams, api.stopStreams = context.WithCancel(context.Background())
+	api.activeConfig.Store(cfg)
+	api.activeEnibra.Store(newEnibraClient(cfg.Enibra))
+
+	p, err := rbac.NewPolicy(api.dataPath("roles.json"))
+	if err != nil {
+		log.Printf("[ERROR] roles: %v (varsayılan yetki matrisi kullanılıyor)", err)
+	}
+	api.activePolicy.Store(p)
+	api.installBlobStore(cfg.Blob)
+	return api
+}
+
+// Reconfigure swaps in a reloaded configuration. The Enibra client (and its
+// cache) is only rebuilt when Enibra settings changed; a new allowlist file
+// is read before anything is swapped so a broken file rejects the reload.
+func (api *API) Reconfigure(cfg *config.Config) error {
+	prev := api.currentConfig()
+
+	var newAllow map[string]allowItem
+	if cfg.AllowlistFile != prev.AllowlistFile {
+		m, err := readAllowFile(cfg.AllowlistFile)
+		if err != nil {
+			return fmt.Errorf("allowlist %s: %w", cfg.AllowlistFile, err)
+		}
+		newAllow = m
+	}
+
+	api.activeConfig.Store(cfg)
+	if cfg.Enibra != prev.Enibra {
+		api.activeEnibra.Store(newEnibraClient(cfg.Enibra))
+		log.Printf("[INFO] enibra client rebuilt")
+	}
+	if newAllow != nil {
+		api.ensureLoaded() // once'ı tüket ki eski dosya sonradan yüklenmesin
+		api.allowDB.Lock()
+		api.allowDB.ByTC = newAllow
+		api.allowLoadErr = nil
+		api.allowDB.Unlock()
+		log.Printf("[INFO] allowlist reloaded from %s (%d entries)", cfg.AllowlistFile, len(newAllow))
+	}
+	return nil
+}
+
+func (api *API) currentConfig() *config.Config {
+	return api.activeConfig.Load()
+}
+
+// Policy returns the role/permission matrix loaded by New.
+func (api *API) Policy() *rbac.Policy {
+	return api.activePolicy.Load()
+}
+
+// enibra returns the shared client so the response cache survives across requests.
+func (api *API) enibra() *enibraClient {
+	return api.activeEnibra.Load()
+}
+
+func (api *API) installBlobStore(cfg config.BlobConfig) {
+	bs, err := storage.New(cfg)
+	if err != nil {
+		log.Printf("[ERROR] blob store: %v (ekler devre dışı)", err)
+		api.activeBlobs.Store(nil)
+		return
+	}
+	api.activeBlobs.Store(&bs)
+}
+
+// blobs returns the attachment store, or nil when it could not be set up.
+func (api *API) blobs() storage.BlobStore {
+	if p := api.activeBlobs.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// dataPath resolves name inside the configured data directory.
+func (api *API) dataPath(name string) string {
+	return filepath.Join(api.currentConfig().DataDir, name)
+}
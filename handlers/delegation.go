@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+
+	"github.com/gorilla/mux"
+)
+
+// maxDelegation bounds how long a role can be handed over in one grant.
+const maxDelegation = 90 * 24 * time.Hour
+
+type delegationInput struct {
+	TC         string     `json:"tc"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Reason     string     `json:"reason"`
+}
+
+func (in *delegationInput) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	errs = requireField(errs, "tc", in.TC)
+	errs = tcField(errs, "tc", in.TC)
+	errs = requireField(errs, "reason", in.Reason)
+	errs = maxLenField(errs, "reason", in.Reason, 500)
+	if in.ValidUntil == nil {
+		errs = append(errs, apierror.FieldError{Field: "valid_until", Code: "required"})
+	}
+	return errs
+}
+
+// delegator returns the caller's own active, non-delegated entry. Yetkiyi
+// yalnızca doğrudan allowlist'e eklenmiş kişi devredebilir.
+func (api *API) delegator(w http.ResponseWriter, r *http.Request) (allowItem, bool) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": AuthHeader + " header required"})
+		return allowItem{}, false
+	}
+	api.ensureLoaded()
+	api.allowDB.RLock()
+	it, ok := api.allowDB.ByTC[tc]
+	api.allowDB.RUnlock()
+	if !ok || !it.activeAt(time.Now()) || it.DelegatedFrom != "" {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "no delegable allowlist entry"})
+		return allowItem{}, false
+	}
+	return it, true
+}
+
+// GET /api/allowlist/delegations
+// Çağıranın devrettiği yetkiler.
+func (api *API) ListDelegations(w http.ResponseWriter, r *http.Request) {
+	from, ok := api.delegator(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	list := []allowView{}
+	api.allowDB.RLock()
+	for _, it := range api.allowDB.ByTC {
+		if it.DelegatedFrom == from.TC {
+			list = append(list, allowView{allowItem: it, Status: it.status(now)})
+		}
+	}
+	api.allowDB.RUnlock()
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(list), "delegations": list})
+}
+
+// POST /api/allowlist/delegations
+// body: {"tc":"...","valid_until":"2026-08-01T00:00:00+03:00","reason":"izin"}
+// Çağıran kendi rolünü belirtilen süre için başka bir TC'ye devreder.
+func (api *API) CreateDelegation(w http.ResponseWriter, r *http.Request) {
+	from, ok := api.delegator(w, r)
+	if !ok {
+		return
+	}
+	var in delegationInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	in.TC = identity.Normalize(in.TC)
+
+	now := time.Now()
+	fields := validateWindow(in.ValidFrom, in.ValidUntil, now)
+	start := now
+	if in.ValidFrom != nil {
+		start = *in.ValidFrom
+	}
+	switch {
+	case in.TC == from.TC:
+		fields = append(fields, apierror.FieldError{Field: "tc", Code: "invalid", Message: "cannot delegate to yourself"})
+	case in.ValidUntil.Sub(start) > maxDelegation:
+		fields = append(fields, apierror.FieldError{Field: "valid_until", Code: "invalid", Message: "at most 90 days"})
+	case from.ValidUntil != nil && in.ValidUntil.After(*from.ValidUntil):
+		fields = append(fields, apierror.FieldError{Field: "valid_until", Code: "invalid", Message: "cannot outlast your own access"})
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": fields})
+		return
+	}
+
+	item := allowItem{
+		TC:            in.TC,
+		Role:          from.Role,
+		Scope:         from.Scope,
+		Branch:        from.Branch,
+		Region:        from.Region,
+		ValidFrom:     in.ValidFrom,
+		ValidUntil:    in.ValidUntil,
+		GrantedBy:     from.TC,
+		Reason:        in.Reason,
+		DelegatedFrom: from.TC,
+	}
+
+	api.allowDB.Lock()
+	prev, existed := api.allowDB.ByTC[in.TC]
+	if existed && prev.DelegatedFrom == "" && prev.status(now) != "expired" {
+		api.allowDB.Unlock()
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: "tc", Code: "invalid", Message: "already on the allowlist",
+		}}})
+		return
+	}
+	api.allowDB.ByTC[in.TC] = item
+	api.allowDB.Unlock()
+
+	if err := api.persist(); err != nil {
+		log.Println("allowlist kaydedilemedi:", err)
+	}
+	log.Printf("[INFO] allowlist: %s rolünü %s kişisine devretti (role=%s)", identity.Mask(from.TC), identity.Mask(in.TC), item.Role)
+	var before any
+	if existed {
+		before = prev
+	}
+	api.recordAudit(r, "allowlist.delegate", in.TC, before, item)
+
+	writeJSON(w, http.StatusCreated, map[string]any{"ok": true, "item": item})
+}
+
+// DELETE /api/allowlist/delegations/{tc}
+// Devri süresinden önce geri alır.
+func (api *API) RevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	from, ok := api.delegator(w, r)
+	if !ok {
+		return
+	}
+	tc := identity.Normalize(mux.Vars(r)["tc"])
+
+	api.allowDB.Lock()
+	prev, existed := api.allowDB.ByTC[tc]
+	if !existed || prev.DelegatedFrom != from.TC {
+		api.allowDB.Unlock()
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	delete(api.allowDB.ByTC, tc)
+	api.allowDB.Unlock()
+
+	if err := api.persist(); err != nil {
+		log.Println("allowlist silme kaydi yazilamadi:", err)
+	}
+	api.recordAudit(r, "allowlist.revoke_delegation", tc, prev, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "tc": tc})
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
 )
 
 type DeviceToken struct {
@@ -17,13 +20,13 @@ type DeviceToken struct {
 	UpdatedAt  string `json:"updated_at"`
 }
 
-func tokensFile() string {
-	_ = os.MkdirAll("data", 0755)
-	return filepath.Join("data", "device_tokens.json")
+func (api *API) tokensFile() string {
+	_ = os.MkdirAll(api.currentConfig().DataDir, 0755)
+	return api.dataPath("device_tokens.json")
 }
 
-func readTokens() ([]DeviceToken, error) {
-	b, err := os.ReadFile(tokensFile())
+func (api *API) readTokens() ([]DeviceToken, error) {
+	b, err := os.ReadFile(api.tokensFile())
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []DeviceToken{}, nil
@@ -37,35 +40,59 @@ func readTokens() ([]DeviceToken, error) {
 	return list, nil
 }
 
-func writeTokens(list []DeviceToken) error {
+func (api *API) writeTokens(list []DeviceToken) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("device_tokens", start, err) }(time.Now())
+
 	b, _ := json.MarshalIndent(list, "", "  ")
-	tmp := tokensFile() + ".tmp"
+	tmp := api.tokensFile() + ".tmp"
 	if err := os.WriteFile(tmp, b, 0644); err != nil {
 		return err
 	}
-	return os.Rename(tmp, tokensFile())
+	return os.Rename(tmp, api.tokensFile())
+}
+
+func (d *DeviceToken) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	errs = requireField(errs, "tc", d.TCKimlikNo)
+	errs = tcField(errs, "tc", d.TCKimlikNo)
+	errs = requireField(errs, "token", d.Token)
+	errs = maxLenField(errs, "token", d.Token, 4096)
+	switch strings.ToLower(strings.TrimSpace(d.Platform)) {
+	case "", "ios", "android":
+	default:
+		errs = append(errs, apierror.FieldError{Field: "platform", Code: "invalid", Message: "ios | android"})
+	}
+	return errs
+}
+
+type unregisterInput struct {
+	TCKimlikNo string `json:"tc"`
+	Token      string `json:"token"`
+}
+
+func (u *unregisterInput) Validate() []apierror.FieldError {
+	var errs []apierror.FieldError
+	errs = requireField(errs, "tc", u.TCKimlikNo)
+	errs = tcField(errs, "tc", u.TCKimlikNo)
+	errs = requireField(errs, "token", u.Token)
+	return errs
 }
 
 // POST /api/device/register
 // Body: { "tc":"...", "platform":"android|ios", "token":"FCM_TOKEN" }
-func RegisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
+func (api *API) RegisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var in DeviceToken
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "bad_request", http.StatusBadRequest)
+	if !decodeJSON(w, r, &in) {
 		return
 	}
-	in.TCKimlikNo = strings.TrimSpace(in.TCKimlikNo)
+	in.TCKimlikNo = identity.Normalize(in.TCKimlikNo)
 	in.Platform = strings.ToLower(strings.TrimSpace(in.Platform))
 	in.Token = strings.TrimSpace(in.Token)
-	if in.TCKimlikNo == "" || in.Token == "" {
-		http.Error(w, "tc_or_token_missing", http.StatusBadRequest)
-		return
-	}
 	in.UpdatedAt = time.Now().Format(time.RFC3339)
 
-	list, err := readTokens()
+	list, err := api.readTokens()
 	if err != nil {
-		http.Error(w, "read_error", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
 		return
 	}
 
@@ -82,34 +109,27 @@ func RegisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 		list = append(list, in)
 	}
 
-	if err := writeTokens(list); err != nil {
-		http.Error(w, "persist_error", http.StatusInternalServerError)
+	if err := api.writeTokens(list); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
 		return
 	}
+	api.recordAudit(r, "device.register", in.TCKimlikNo, nil, map[string]any{"platform": in.Platform, "replaced": replaced})
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // POST /api/device/unregister
 // Body: { "tc":"...", "token":"FCM_TOKEN" }
-func UnregisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
-	var in struct {
-		TCKimlikNo string `json:"tc"`
-		Token      string `json:"token"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "bad_request", http.StatusBadRequest)
+func (api *API) UnregisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var in unregisterInput
+	if !decodeJSON(w, r, &in) {
 		return
 	}
-	in.TCKimlikNo = strings.TrimSpace(in.TCKimlikNo)
+	in.TCKimlikNo = identity.Normalize(in.TCKimlikNo)
 	in.Token = strings.TrimSpace(in.Token)
-	if in.TCKimlikNo == "" || in.Token == "" {
-		http.Error(w, "tc_or_token_missing", http.StatusBadRequest)
-		return
-	}
 
-	list, err := readTokens()
+	list, err := api.readTokens()
 	if err != nil {
-		http.Error(w, "read_error", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.StoreReadFailed, nil)
 		return
 	}
 
@@ -120,9 +140,11 @@ func UnregisterDeviceTokenHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := writeTokens(out); err != nil {
-		http.Error(w, "persist_error", http.StatusInternalServerError)
+	removed := len(list) - len(out)
+	if err := api.writeTokens(out); err != nil {
+		apierror.Write(w, r, apierror.StoreWriteFailed, nil)
 		return
 	}
+	api.recordAudit(r, "device.unregister", in.TCKimlikNo, nil, map[string]any{"removed": removed})
 	w.WriteHeader(http.StatusNoContent)
 }
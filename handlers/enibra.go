@@ -10,12 +10,16 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/config"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
 )
 
 // ===================== Client & Cache =====================
@@ -41,49 +45,37 @@ type cachedItem struct {
 	body     []byte
 }
 
-func newEnibraClientFromEnv() *enibraClient {
-	base := strings.TrimRight(os.Getenv("ENIBRA_BASE_URL"), "/")
-	mus := os.Getenv("ENIBRA_MUSTERI_KODU")
-	par := os.Getenv("ENIBRA_PAROLA")
-	host := os.Getenv("ENIBRA_HOST_HEADER")
-
-	// timeout
-	tout := 10 * time.Second
-	if ms, _ := strconv.Atoi(os.Getenv("ENIBRA_TIMEOUT_MS")); ms > 0 {
-		tout = time.Duration(ms) * time.Millisecond
-	}
-
-	// cache ttl
-	ttl := 30 * time.Second
-	if sec, _ := strconv.Atoi(os.Getenv("ENIBRA_CACHE_SEC")); sec > 0 {
-		ttl = time.Duration(sec) * time.Second
-	}
-
+func newEnibraClient(cfg config.EnibraConfig) *enibraClient {
 	tr := &http.Transport{}
-	if strings.HasPrefix(strings.ToLower(base), "https://") {
-		insecure := os.Getenv("ENIBRA_INSECURE_TLS") == "1"
+	if strings.HasPrefix(strings.ToLower(cfg.BaseURL), "https://") {
 		tr.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: insecure,
-			ServerName:         host,
+			InsecureSkipVerify: cfg.InsecureTLS,
+			ServerName:         cfg.HostHeader,
 		}
 	}
 
 	return &enibraClient{
-		base:       base,
-		hostHeader: host,
-		musteri:    mus,
-		parola:     par,
-		http:       &http.Client{Timeout: tout, Transport: tr},
-		cache:      &enibraCache{ttl: ttl},
+		base:       cfg.BaseURL,
+		hostHeader: cfg.HostHeader,
+		musteri:    cfg.MusteriKodu,
+		parola:     cfg.Parola,
+		http:       &http.Client{Timeout: cfg.Timeout, Transport: tr},
+		cache:      &enibraCache{ttl: cfg.CacheTTL},
 	}
 }
 
+func (c *enibraClient) configured() bool {
+	return c.base != "" && c.musteri != "" && c.parola != ""
+}
+
 func (c *enibraClient) personelListesi(ctx context.Context, extra url.Values) (int, []byte, string, error) {
 	// cache key = path + query
 	key := "PersonelListesi.doms?" + extra.Encode()
 	if b, ct, st, ok := c.cacheGet(key); ok {
+		metrics.EnibraCache.Inc("hit")
 		return st, b, ct, nil
 	}
+	metrics.EnibraCache.Inc("miss")
 
 	q := url.Values{}
 	q.Set("MUSTERI_KODU", c.musteri)
@@ -103,13 +95,22 @@ func (c *enibraClient) personelListesi(ctx context.Context, extra url.Values) (i
 		req.Header.Set("Host", c.hostHeader)
 	}
 
+	start := time.Now()
 	resp, err := c.http.Do(req)
 	if err != nil {
+		metrics.ObserveEnibra("PersonelListesi", start, 0, err)
+		recordEnibraFetch(err)
 		return 0, nil, "", err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
+	metrics.ObserveEnibra("PersonelListesi", start, resp.StatusCode, nil)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		recordEnibraFetch(fmt.Errorf("status=%d", resp.StatusCode))
+	} else {
+		recordEnibraFetch(nil)
+	}
 	ct := resp.Header.Get("Content-Type")
 	if ct == "" {
 		ct = "application/json; charset=utf-8"
@@ -143,10 +144,10 @@ func (c *enibraClient) cacheSet(key string, body []byte, ct string, status int)
 
 // GET /api/enibra/personeller
 // Upstream ne dönerse aynen geçirir (JSON/CT vs. korunur)
-func EnibraPersonelListesiProxy(w http.ResponseWriter, r *http.Request) {
-	cli := newEnibraClientFromEnv()
-	if cli.base == "" || cli.musteri == "" || cli.parola == "" {
-		respondJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "server_not_configured"})
+func (api *API) EnibraPersonelListesiProxy(w http.ResponseWriter, r *http.Request) {
+	cli := api.enibra()
+	if !cli.configured() {
+		apierror.Write(w, r, apierror.ServerNotConfigured, nil)
 		return
 	}
 
@@ -163,16 +164,29 @@ func EnibraPersonelListesiProxy(w http.ResponseWriter, r *http.Request) {
 	status, body, ct, err := cli.personelListesi(ctx, extra)
 	if err != nil {
 		log.Printf("[enibra] upstream error: %v", err)
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_upstream_error"})
+		apierror.Write(w, r, apierror.EnibraUpstream, nil)
 		return
 	}
 
 	// HTML hata sayfası gelirse 502 verelim
 	if strings.Contains(strings.ToLower(ct), "text/html") {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_error_html"})
+		apierror.Write(w, r, apierror.EnibraHTML, nil)
 		return
 	}
 
+	if status >= 200 && status < 300 {
+		scope, ok := api.resolveScope(w, r, personnelArea, personnelRows(body))
+		if !ok {
+			return
+		}
+		if scope.level != scopeCompany {
+			if body, ok = filterPersonnelBody(body, scope); !ok {
+				apierror.Write(w, r, apierror.EnibraInvalidJSON, nil)
+				return
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", ct)
 	w.WriteHeader(status)
 	_, _ = w.Write(body)
@@ -183,16 +197,15 @@ func EnibraPersonelListesiProxy(w http.ResponseWriter, r *http.Request) {
 // GET /api/enibra/detay
 // Upstream JSON’unu sadeleştirir + arama/sayfalama uygular
 // handlers/enibra.go içindeki EnibraPersonelDetay'ı bununla değiştir
-func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
-	tc := strings.TrimSpace(r.URL.Query().Get("tc"))
-	if tc == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_tc"})
+func (api *API) EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
+	tc, ok := queryTC(w, r)
+	if !ok {
 		return
 	}
 
-	cli := newEnibraClientFromEnv()
-	if cli.base == "" || cli.musteri == "" || cli.parola == "" {
-		respondJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "server_not_configured"})
+	cli := api.enibra()
+	if !cli.configured() {
+		apierror.Write(w, r, apierror.ServerNotConfigured, nil)
 		return
 	}
 
@@ -203,12 +216,12 @@ func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
 	// Tüm listeyi çek (cache'li)
 	status, body, ct, err := cli.personelListesi(ctx, url.Values{})
 	if err != nil || status < 200 || status >= 300 {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_upstream_error"})
+		apierror.Write(w, r, apierror.EnibraUpstream, nil)
 		return
 	}
 	// HTML geldiyse (hata sayfası vb.)
 	if strings.Contains(strings.ToLower(ct), "text/html") {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_error_html"})
+		apierror.Write(w, r, apierror.EnibraHTML, nil)
 		return
 	}
 
@@ -244,18 +257,17 @@ func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(items) == 0 {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "non_json_or_empty"})
+		apierror.Write(w, r, apierror.EnibraEmpty, nil)
 		return
 	}
 
 	// TC ile kaydı bul
 	row := func(list []map[string]any, want string) map[string]any {
-		want = strings.TrimSpace(want)
 		keys := []string{"TC_KIMLIK_NO", "TC", "TC_NO", "tc", "tckimlik"}
 		for _, it := range list {
 			for _, k := range keys {
 				if v, ok := it[k]; ok {
-					if strings.TrimSpace(asStr(v)) == want {
+					if identity.Normalize(asStr(v)) == want {
 						return it
 					}
 				}
@@ -265,7 +277,15 @@ func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
 	}(items, tc)
 
 	if row == nil {
-		respondJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+		apierror.Write(w, r, apierror.NotFound, nil)
+		return
+	}
+	scope, ok := api.resolveScope(w, r, personnelArea, items)
+	if !ok {
+		return
+	}
+	if !scope.allows(row) {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"scope": scope.level.String()})
 		return
 	}
 
@@ -278,35 +298,16 @@ func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
 		}
 		return ""
 	}
-	norm := func(s string) string {
-		s = strings.ToLower(strings.TrimSpace(s))
-		r := strings.NewReplacer("ğ", "g", "ü", "u", "ş", "s", "ı", "i", "ö", "o", "ç", "c")
-		return r.Replace(s)
-	}
-
 	ad := pickStr(row, "ADI", "AD", "ad")
 	soyad := pickStr(row, "SOYADI", "SOYAD", "soyad")
 	tcOut := pickStr(row, "TC_KIMLIK_NO", "TC", "TC_NO", "tc", "tckimlik")
 
-	// Şube / görev yeri ismi
-	subeAdi := pickStr(row, "SUBE", "GOREV_YERI", "ISYERI", "ISYERI_ADI", "sube")
-	ham := norm(subeAdi + " " + pickStr(row, "ISYERI_TIPI", "BOLUM", "DEPARTMAN"))
-
-	konum := "BILINMIYOR"
-	switch {
-	case strings.Contains(ham, "genel") || strings.Contains(ham, "merkez") || strings.Contains(ham, "gm"):
-		konum = "GENEL_MERKEZ"
-	case strings.Contains(ham, "magaza") || strings.Contains(ham, "mağaza") ||
-		strings.Contains(ham, "satis") || strings.Contains(ham, "satış"):
-		konum = "MAGAZA"
-	}
-
 	respondJSON(w, http.StatusOK, map[string]any{
 		"tc":         tcOut,
 		"ad":         ad,
 		"soyad":      soyad,
-		"sube_adi":   subeAdi,
-		"konum_tipi": konum, // "GENEL_MERKEZ" | "MAGAZA" | "BILINMIYOR"
+		"sube_adi":   rowBranch(row),
+		"konum_tipi": konumTipi(row), // "GENEL_MERKEZ" | "MAGAZA" | "BILINMIYOR"
 	})
 }
 
@@ -315,12 +316,12 @@ func EnibraPersonelDetay(w http.ResponseWriter, r *http.Request) {
 // GET /api/enibra/vardiya-uyarilari
 // Vardiyası belirli bir saatte başlayıp kart basmamış (GIRIS_SAATI boş) personelleri listeler.
 // Varsayılan kontrol saati now(), tolerans (grace) 20 dakikadır.
-func EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
+func (api *API) EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
 	checkAt := time.Now().In(time.Local)
 	if v := strings.TrimSpace(r.URL.Query().Get("check_time")); v != "" {
 		parsed, err := parseFlexibleTime(v, checkAt)
 		if err != nil {
-			respondJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_check_time"})
+			apierror.Write(w, r, apierror.InvalidCheckTime, nil)
 			return
 		}
 		checkAt = parsed
@@ -330,7 +331,7 @@ func EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
 	if v := strings.TrimSpace(r.URL.Query().Get("grace_min")); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil || n < 0 {
-			respondJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grace_minutes"})
+			apierror.Write(w, r, apierror.InvalidGrace, nil)
 			return
 		}
 		grace = n
@@ -339,9 +340,9 @@ func EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
 	targetStart := checkAt.Add(-time.Duration(grace) * time.Minute)
 	wantHour, wantMinute := targetStart.Hour(), targetStart.Minute()
 
-	cli := newEnibraClientFromEnv()
-	if cli.base == "" || cli.musteri == "" || cli.parola == "" {
-		respondJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "server_not_configured"})
+	cli := api.enibra()
+	if !cli.configured() {
+		apierror.Write(w, r, apierror.ServerNotConfigured, nil)
 		return
 	}
 
@@ -350,11 +351,11 @@ func EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
 
 	status, body, ct, err := cli.personelListesi(ctx, url.Values{})
 	if err != nil || status < 200 || status >= 300 {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_upstream_error"})
+		apierror.Write(w, r, apierror.EnibraUpstream, nil)
 		return
 	}
 	if strings.Contains(strings.ToLower(ct), "text/html") {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_error_html"})
+		apierror.Write(w, r, apierror.EnibraHTML, nil)
 		return
 	}
 
@@ -364,61 +365,74 @@ func EnibraVardiyaUyarilari(w http.ResponseWriter, r *http.Request) {
 			SonucMesaji []map[string]any `json:"SONUC_MESAJI"`
 		}
 		if err := json.Unmarshal(body, &wrapper); err != nil {
-			respondJSON(w, http.StatusBadGateway, map[string]any{"error": "invalid_upstream_json"})
+			apierror.Write(w, r, apierror.EnibraInvalidJSON, nil)
 			return
 		}
 		rows = wrapper.SonucMesaji
 	}
 	if len(rows) == 0 {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "empty_upstream"})
+		apierror.Write(w, r, apierror.EnibraEmpty, nil)
 		return
 	}
+	scope, ok := api.resolveScope(w, r, attendanceArea, rows)
+	if !ok {
+		return
+	}
+	rows = scope.filter(rows)
 
 	missing := make([]map[string]any, 0)
 	for _, rec := range rows {
-		startVal := strings.TrimSpace(anyToString(rec["VARDIYA_BASLANGIC"]))
-		hour, minute, ok := extractHourMinute(startVal)
-		if !ok || hour != wantHour || minute != wantMinute {
+		hour, minute, ok := shiftStart(rec)
+		if !ok || hour != wantHour || minute != wantMinute || clockedIn(rec) {
 			continue
 		}
-
-		giris := strings.TrimSpace(anyToString(rec["GIRIS_SAATI"]))
-		if giris != "" {
-			continue
-		}
-
-		missing = append(missing, map[string]any{
-			"tc":                strings.TrimSpace(anyToString(firstNonEmpty(rec, "TC_KIMLIK_NO", "TC", "TC_NO", "tc", "tckimlik"))),
-			"ad":                strings.TrimSpace(anyToString(firstNonEmpty(rec, "ADI", "AD", "ad"))),
-			"soyad":             strings.TrimSpace(anyToString(firstNonEmpty(rec, "SOYADI", "SOYAD", "soyad"))),
-			"vardiya_baslangic": startVal,
-			"giris_saati":       giris,
-		})
+		missing = append(missing, absenceItem(rec))
 	}
 
 	respondJSON(w, http.StatusOK, map[string]any{
 		"check_time":          checkAt.Format(time.RFC3339),
 		"grace_minutes":       grace,
 		"target_shift_hour":   fmt.Sprintf("%02d:%02d", wantHour, wantMinute),
+		"scope":               scope.level.String(),
 		"missing_entry_count": len(missing),
 		"items":               missing,
 	})
 }
 
+// shiftStart parses VARDIYA_BASLANGIC of rec.
+func shiftStart(rec map[string]any) (hour, minute int, ok bool) {
+	return extractHourMinute(strings.TrimSpace(anyToString(rec["VARDIYA_BASLANGIC"])))
+}
+
+// clockedIn: GIRIS_SAATI doluysa kart basılmıştır.
+func clockedIn(rec map[string]any) bool {
+	return strings.TrimSpace(anyToString(rec["GIRIS_SAATI"])) != ""
+}
+
+// absenceItem is one entry of the shift warning list.
+func absenceItem(rec map[string]any) map[string]any {
+	return map[string]any{
+		"tc":                strings.TrimSpace(anyToString(firstNonEmpty(rec, "TC_KIMLIK_NO", "TC", "TC_NO", "tc", "tckimlik"))),
+		"ad":                strings.TrimSpace(anyToString(firstNonEmpty(rec, "ADI", "AD", "ad"))),
+		"soyad":             strings.TrimSpace(anyToString(firstNonEmpty(rec, "SOYADI", "SOYAD", "soyad"))),
+		"vardiya_baslangic": strings.TrimSpace(anyToString(rec["VARDIYA_BASLANGIC"])),
+		"giris_saati":       strings.TrimSpace(anyToString(rec["GIRIS_SAATI"])),
+	}
+}
+
 // ===================== Single record by TC =====================
 
 // GET /api/enibra/personel?tc=XXXXXXXXXXX
-func EnibraPersonelByTC(w http.ResponseWriter, r *http.Request) {
-	tc := strings.TrimSpace(r.URL.Query().Get("tc"))
-	if tc == "" {
-		respondJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_tc"})
+func (api *API) EnibraPersonelByTC(w http.ResponseWriter, r *http.Request) {
+	tc, ok := queryTC(w, r)
+	if !ok {
 		return
 	}
 
 	// normalize endpointini kullanıp TC filtreliyoruz (performans: cache aktif)
-	cli := newEnibraClientFromEnv()
-	if cli.base == "" || cli.musteri == "" || cli.parola == "" {
-		respondJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "server_not_configured"})
+	cli := api.enibra()
+	if !cli.configured() {
+		apierror.Write(w, r, apierror.ServerNotConfigured, nil)
 		return
 	}
 
@@ -427,7 +441,7 @@ func EnibraPersonelByTC(w http.ResponseWriter, r *http.Request) {
 
 	status, body, _, err := cli.personelListesi(ctx, url.Values{})
 	if err != nil || status < 200 || status >= 300 {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "enibra_upstream_error"})
+		apierror.Write(w, r, apierror.EnibraUpstream, nil)
 		return
 	}
 
@@ -435,7 +449,7 @@ func EnibraPersonelByTC(w http.ResponseWriter, r *http.Request) {
 		SonucMesaji []map[string]any `json:"SONUC_MESAJI"`
 	}
 	if err := json.Unmarshal(body, &root); err != nil {
-		respondJSON(w, http.StatusBadGateway, map[string]any{"error": "invalid_upstream_json"})
+		apierror.Write(w, r, apierror.EnibraInvalidJSON, nil)
 		return
 	}
 
@@ -443,17 +457,41 @@ func EnibraPersonelByTC(w http.ResponseWriter, r *http.Request) {
 	keys := []string{"TC_KIMLIK_NO", "TC", "TCKN", "TC_NO", "tc", "tckimlik"}
 	for _, m := range root.SonucMesaji {
 		for _, k := range keys {
-			if as := strings.TrimSpace(anyToString(m[k])); as != "" && as == tc {
+			if as := identity.Normalize(anyToString(m[k])); as != "" && as == tc {
+				scope, ok := api.resolveScope(w, r, personnelArea, root.SonucMesaji)
+				if !ok {
+					return
+				}
+				if !scope.allows(m) {
+					apierror.Write(w, r, apierror.Forbidden, map[string]any{"scope": scope.level.String()})
+					return
+				}
 				respondJSON(w, http.StatusOK, m)
 				return
 			}
 		}
 	}
-	respondJSON(w, http.StatusNotFound, map[string]any{"error": "not_found"})
+	apierror.Write(w, r, apierror.NotFound, nil)
 }
 
 // ===================== helpers =====================
 
+// queryTC reads ?tc=, normalizes it and writes missing_tc / invalid_tc when
+// it is unusable.
+func queryTC(w http.ResponseWriter, r *http.Request) (string, bool) {
+	raw := r.URL.Query().Get("tc")
+	if strings.TrimSpace(raw) == "" {
+		apierror.Write(w, r, apierror.MissingTC, nil)
+		return "", false
+	}
+	tc := identity.Normalize(raw)
+	if err := identity.Validate(tc); err != nil {
+		apierror.Write(w, r, apierror.InvalidTC, map[string]any{"reason": err.Error()})
+		return "", false
+	}
+	return tc, true
+}
+
 func respondJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
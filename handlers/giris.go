@@ -1,28 +1,88 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/models"
 )
 
-func GirisHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		TCKimlikNo string `json:"tc_kimlik_no"`
+// POST /api/giris
+// TC, Enibra personel listesinde ya da aktif bir allowlist kaydında yoksa 401
+// döner; bu yanıt giriş kilidini (middlewares.RateLimits.Login) besler.
+// Enibra'ya ulaşılamazsa 502 döner ve deneme başarısız sayılmaz. Başarılı
+// girişte dönen token sonraki isteklerde "Authorization: Bearer" ile gelir.
+func (api *API) GirisHandler(w http.ResponseWriter, r *http.Request) {
+	var input models.GirisRequest
+	if !decodeJSON(w, r, &input) {
+		api.recordAudit(r, "auth.login_failed", identity.Normalize(input.TCKimlikNo), nil, nil)
+		return
 	}
+	tc := identity.Normalize(input.TCKimlikNo)
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.TCKimlikNo == "" {
-		http.Error(w, "Geçersiz veri", http.StatusBadRequest)
+	cli := api.enibra()
+	if !cli.configured() {
+		apierror.Write(w, r, apierror.ServerNotConfigured, nil)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), cli.http.Timeout)
+	defer cancel()
+	status, body, _, err := cli.personelListesi(ctx, url.Values{})
+	if err != nil || status < 200 || status >= 300 {
+		apierror.Write(w, r, apierror.EnibraUpstream, nil)
 		return
 	}
 
+	var row map[string]any
+	for _, m := range personnelRows(body) {
+		if rowTC(m) == tc {
+			row = m
+			break
+		}
+	}
+	now := time.Now()
+	api.ensureLoaded()
+	api.allowDB.RLock()
+	it, allowed := api.allowDB.ByTC[tc]
+	api.allowDB.RUnlock()
+	allowed = allowed && it.activeAt(now)
+
+	if row == nil && !allowed {
+		api.recordAudit(r, "auth.login_failed", tc, nil, nil)
+		apierror.Write(w, r, apierror.LoginFailed, nil)
+		return
+	}
+	token, exp, err := api.issueSession(tc, now)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal, nil)
+		return
+	}
+	api.recordAudit(r, "auth.login", tc, nil, nil)
+
+	// Enibra'da olmayan allowlist kaydı (ör. dış yönetici) allowlist adıyla döner.
 	resp := map[string]any{
-		"tc":       input.TCKimlikNo,
-		"ad":       "Yusuf",
-		"soyad":    "Eren",
-		"gorev":    "Bilgi İşlem",
-		"sube":     "Merkez",
-		"insan_id": 1,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+		"tc":               tc,
+		"ad":               strings.TrimSpace(it.Name),
+		"soyad":            "",
+		"gorev":            "",
+		"sube":             it.Branch,
+		"insan_id":         nil,
+		"token":            token,
+		"token_expires_at": exp.UTC().Format(time.RFC3339),
+	}
+	if row != nil {
+		resp["ad"] = strings.TrimSpace(anyToString(firstNonEmpty(row, "ADI", "AD", "ad")))
+		resp["soyad"] = strings.TrimSpace(anyToString(firstNonEmpty(row, "SOYADI", "SOYAD", "soyad")))
+		resp["gorev"] = rowGorev(row)
+		resp["sube"] = rowBranch(row)
+		if id := firstNonEmpty(row, "INSAN_ID", "PERSONEL_ID", "ID"); id != "" {
+			resp["insan_id"] = id
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
 }
@@ -1,8 +1,200 @@
 package handlers
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
-// Health returns a simple ok response for readiness checks.
-func Health(w http.ResponseWriter, r *http.Request) {
-	WriteJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+var startedAt = time.Now()
+
+// enibraFreshness: son başarılı Enibra çağrısı bundan eskiyse ve arada hata
+// alındıysa readiness düşer.
+const enibraFreshness = 15 * time.Minute
+
+// enibraErrorWindow: bundan eski bir hata readiness'i düşürmez; istek gelmeyen
+// bir pod eski hatası yüzünden sonsuza dek trafik dışında kalmaz.
+const enibraErrorWindow = 5 * time.Minute
+
+type checkResult struct {
+	Status  string `json:"status"` // "ok" | "fail" | "unknown"
+	Message string `json:"message,omitempty"`
+}
+
+// Health is the liveness probe (GET /healthz): the process is up and serving.
+func (api *API) Health(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{
+		"status":     "ok",
+		"version":    api.appVersion(),
+		"uptime_sec": int64(time.Since(startedAt).Seconds()),
+	})
+}
+
+// Ready is the readiness probe (GET /readyz). It runs every dependency check
+// and answers 503 when any of them fails.
+func (api *API) Ready(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]checkResult{
+		"data_dir":      api.checkDataDir(),
+		"allowlist":     api.checkAllowlist(),
+		"enibra_config": api.checkEnibraConfig(),
+		"enibra_fetch":  checkEnibraFetch(),
+		"workers":       checkWorkers(),
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if c.Status == "fail" {
+			status, code = "fail", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	WriteJSON(w, code, map[string]any{
+		"status":     status,
+		"version":    api.appVersion(),
+		"uptime_sec": int64(time.Since(startedAt).Seconds()),
+		"checks":     checks,
+	})
+}
+
+func (api *API) appVersion() string {
+	return api.currentConfig().AppVersion
+}
+
+func (api *API) checkDataDir() checkResult {
+	dir := api.currentConfig().DataDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return checkResult{Status: "fail", Message: err.Error()}
+	}
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return checkResult{Status: "fail", Message: err.Error()}
+	}
+	name := f.Name()
+	_ = f.Close()
+	_ = os.Remove(name)
+	return checkResult{Status: "ok", Message: filepath.Clean(dir)}
+}
+
+func (api *API) checkAllowlist() checkResult {
+	api.ensureLoaded()
+	api.allowDB.RLock()
+	err := api.allowLoadErr
+	api.allowDB.RUnlock()
+	if err != nil {
+		return checkResult{Status: "fail", Message: err.Error()}
+	}
+	return checkResult{Status: "ok"}
+}
+
+func (api *API) checkEnibraConfig() checkResult {
+	cfg := api.currentConfig().Enibra
+	var missing []string
+	for k, v := range map[string]string{"ENIBRA_BASE_URL": cfg.BaseURL, "ENIBRA_MUSTERI_KODU": cfg.MusteriKodu, "ENIBRA_PAROLA": cfg.Parola} {
+		if v == "" {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		return checkResult{Status: "fail", Message: "missing " + strings.Join(missing, ", ")}
+	}
+	return checkResult{Status: "ok"}
+}
+
+func checkEnibraFetch() checkResult {
+	lastOK, lastErr, lastErrAt := enibraFetchState()
+	failing := lastErrAt.After(lastOK) && (lastOK.IsZero() || time.Since(lastOK) > enibraFreshness)
+	switch {
+	case lastOK.IsZero() && lastErrAt.IsZero():
+		return checkResult{Status: "unknown", Message: "no fetch yet"}
+	case failing && time.Since(lastErrAt) <= enibraErrorWindow:
+		return checkResult{Status: "fail", Message: fmt.Sprintf("last error at %s: %v", lastErrAt.Format(time.RFC3339), lastErr)}
+	case failing:
+		return checkResult{Status: "unknown", Message: "no fetch since error at " + lastErrAt.Format(time.RFC3339)}
+	}
+	return checkResult{Status: "ok", Message: "last success " + lastOK.Format(time.RFC3339)}
+}
+
+func checkWorkers() checkResult {
+	stale := staleWorkers()
+	if len(stale) > 0 {
+		return checkResult{Status: "fail", Message: "stale: " + strings.Join(stale, ", ")}
+	}
+	return checkResult{Status: "ok"}
+}
+
+// ===================== Enibra fetch tracking =====================
+
+var enibraFetch struct {
+	sync.Mutex
+	lastOK    time.Time
+	lastErr   error
+	lastErrAt time.Time
+}
+
+// recordEnibraFetch is called after every upstream Enibra call.
+func recordEnibraFetch(err error) {
+	enibraFetch.Lock()
+	defer enibraFetch.Unlock()
+	if err != nil {
+		enibraFetch.lastErr = err
+		enibraFetch.lastErrAt = time.Now()
+		return
+	}
+	enibraFetch.lastOK = time.Now()
+}
+
+func enibraFetchState() (time.Time, error, time.Time) {
+	enibraFetch.Lock()
+	defer enibraFetch.Unlock()
+	return enibraFetch.lastOK, enibraFetch.lastErr, enibraFetch.lastErrAt
+}
+
+// ===================== Background workers =====================
+
+type workerState struct {
+	interval time.Duration
+	lastBeat time.Time
+}
+
+var workers = struct {
+	sync.Mutex
+	m map[string]*workerState
+}{m: map[string]*workerState{}}
+
+// RegisterWorker declares a background worker that is expected to call
+// WorkerHeartbeat at least once per interval.
+func RegisterWorker(name string, interval time.Duration) {
+	workers.Lock()
+	workers.m[name] = &workerState{interval: interval, lastBeat: time.Now()}
+	workers.Unlock()
+}
+
+// WorkerHeartbeat marks the worker as alive.
+func WorkerHeartbeat(name string) {
+	workers.Lock()
+	if st, ok := workers.m[name]; ok {
+		st.lastBeat = time.Now()
+	}
+	workers.Unlock()
+}
+
+// staleWorkers returns workers that missed two consecutive heartbeats.
+func staleWorkers() []string {
+	workers.Lock()
+	defer workers.Unlock()
+	var out []string
+	for name, st := range workers.m {
+		if time.Since(st.lastBeat) > 2*st.interval {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /api/admin/lockouts
+// Başarısız giriş sayaçları ve aktif kilitler (TC ve IP bazında).
+func ListLockouts(tc, ip *ratelimit.Lockouts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tc": tc.List(),
+			"ip": ip.List(),
+		})
+	}
+}
+
+// DELETE /api/admin/lockouts/{scope}/{key}   scope: tc | ip
+// TC anahtarı login middleware'i gibi identity.Normalize'dan geçer; yoksa
+// "250 315 193 76" yazan yönetici kilidi hiç bulamaz.
+func ClearLockout(tc, ip *ratelimit.Lockouts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		key := strings.TrimSpace(vars["key"])
+		var target *ratelimit.Lockouts
+		switch vars["scope"] {
+		case "tc":
+			target = tc
+			key = identity.Normalize(key)
+		case "ip":
+			target = ip
+		default:
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+				Field: "scope", Code: "invalid", Message: "tc | ip",
+			}}})
+			return
+		}
+		if key == "" || !target.Clear(key) {
+			apierror.Write(w, r, apierror.NotFound, nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "scope": vars["scope"], "key": key})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hys-go-backend/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+func TestClearLockout(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope, key string
+		wantStatus int
+		wantKey    string // temizlenmesi beklenen anahtar
+	}{
+		{"tc", "tc", "25031519376", http.StatusOK, "25031519376"},
+		{"tc with spaces", "tc", "250 315 193 76", http.StatusOK, "25031519376"},
+		{"tc with dashes", "tc", "250-315-193-76", http.StatusOK, "25031519376"},
+		{"ip", "ip", "192.0.2.10", http.StatusOK, "192.0.2.10"},
+		{"unknown tc", "tc", "10000000146", http.StatusNotFound, ""},
+		{"tc without digits", "tc", "abc", http.StatusNotFound, ""},
+		{"bad scope", "user", "25031519376", http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := ratelimit.LockoutPolicy{Threshold: 1, Base: time.Minute, Max: time.Hour}
+			tcLocks := ratelimit.NewLockouts(policy, nil)
+			ipLocks := ratelimit.NewLockouts(policy, nil)
+			tcLocks.Fail("25031519376")
+			ipLocks.Fail("192.0.2.10")
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/admin/lockouts/"+tt.scope+"/x", nil)
+			req = mux.SetURLVars(req, map[string]string{"scope": tt.scope, "key": tt.key})
+			rec := httptest.NewRecorder()
+			ClearLockout(tcLocks, ipLocks).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantKey == "" {
+				return
+			}
+			target := tcLocks
+			if tt.scope == "ip" {
+				target = ipLocks
+			}
+			if locked, _ := target.Locked(tt.wantKey); locked {
+				t.Errorf("%s still locked", tt.wantKey)
+			}
+		})
+	}
+}
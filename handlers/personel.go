@@ -3,16 +3,16 @@ package handlers
 import (
 	"context"
 	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"strings"
 	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/metrics"
 )
 
 var httpClient = &http.Client{
@@ -30,36 +30,44 @@ var httpClient = &http.Client{
 	},
 }
 
-// PersonelList fetches the Enibra JSON and returns it as-is.
-func PersonelList(w http.ResponseWriter, r *http.Request) {
-	body, err := fetchPersonelData(r.Context())
+// PersonelList fetches the Enibra JSON and returns the rows the caller may see.
+func (api *API) PersonelList(w http.ResponseWriter, r *http.Request) {
+	body, err := api.fetchPersonelData(r.Context())
 	if err != nil {
-		status, payload := classifyFetchError(err)
-		WriteJSON(w, status, payload)
+		apierror.Write(w, r, classifyFetchError(err), map[string]any{"cause": err.Error()})
+		return
+	}
+
+	scope, ok := api.resolveScope(w, r, personnelArea, personnelRows(body))
+	if !ok {
 		return
 	}
+	if scope.level != scopeCompany {
+		if body, ok = filterPersonnelBody(body, scope); !ok {
+			apierror.Write(w, r, apierror.EnibraInvalidJSON, nil)
+			return
+		}
+	}
 
 	var parsed any
 	if err := json.Unmarshal(body, &parsed); err != nil {
-		WriteJSON(w, http.StatusBadGateway, map[string]any{
-			"error":   "invalid_enibra_json",
-			"message": err.Error(),
-		})
+		apierror.Write(w, r, apierror.EnibraInvalidJSON, map[string]any{"cause": err.Error()})
 		return
 	}
 
 	WriteJSONValue(w, http.StatusOK, parsed)
 }
 
-func fetchPersonelData(ctx context.Context) ([]byte, error) {
-	base := strings.TrimSpace(os.Getenv("ENIBRA_URL"))
+func (api *API) fetchPersonelData(ctx context.Context) ([]byte, error) {
+	cfg := api.currentConfig().Enibra
+	base := cfg.PersonelURL
 	if base == "" {
 		return nil, fmt.Errorf("%w: ENIBRA_URL missing", errConfig)
 	}
 
-	key, err := resolveEnibraKey()
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", errConfig, err)
+	key := cfg.Key
+	if key == "" {
+		return nil, fmt.Errorf("%w: %v", errConfig, errMissingKey)
 	}
 
 	parsed, err := url.Parse(base)
@@ -80,16 +88,23 @@ func fetchPersonelData(ctx context.Context) ([]byte, error) {
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "hys-go-backend/1.0")
 
+	start := time.Now()
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveEnibra("personeller", start, 0, err)
+		recordEnibraFetch(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	metrics.ObserveEnibra("personeller", start, resp.StatusCode, nil)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		snippet, _ := io.ReadAll(&io.LimitedReader{R: resp.Body, N: 4096})
-		return nil, fmt.Errorf("%w: status=%d body=%s", errUpstreamStatus, resp.StatusCode, string(snippet))
+		err := fmt.Errorf("%w: status=%d body=%s", errUpstreamStatus, resp.StatusCode, string(snippet))
+		recordEnibraFetch(err)
+		return nil, err
 	}
+	recordEnibraFetch(nil)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -109,19 +124,18 @@ var (
 	errUpstreamStatus = errors.New("upstream_status_error")
 	errInvalidJSON    = errors.New("invalid_json")
 	errMissingKey     = errors.New("missing_enibra_key")
-	errDecodeKey      = errors.New("decode_enibra_key_failed")
 )
 
-func classifyFetchError(err error) (int, map[string]any) {
+func classifyFetchError(err error) apierror.Code {
 	switch {
 	case errors.Is(err, errConfig):
-		return http.StatusServiceUnavailable, map[string]any{"error": "configuration_error", "message": err.Error()}
+		return apierror.ServerNotConfigured
 	case errors.Is(err, errInvalidJSON):
-		return http.StatusBadGateway, map[string]any{"error": "invalid_enibra_json", "message": err.Error()}
+		return apierror.EnibraInvalidJSON
 	case errors.Is(err, errUpstreamStatus):
-		return http.StatusBadGateway, map[string]any{"error": "enibra_status_error", "message": err.Error()}
+		return apierror.EnibraStatus
 	default:
-		return http.StatusBadGateway, map[string]any{"error": "enibra_request_failed", "message": err.Error()}
+		return apierror.EnibraUpstream
 	}
 }
 
@@ -139,20 +153,3 @@ func WriteJSONValue(w http.ResponseWriter, status int, payload any) {
 	w.WriteHeader(status)
 	_ = enc.Encode(payload)
 }
-
-func resolveEnibraKey() (string, error) {
-	if key := strings.TrimSpace(os.Getenv("ENIBRA_KEY")); key != "" {
-		return key, nil
-	}
-
-	encoded := strings.TrimSpace(os.Getenv("ENIBRA_KEY_ENC"))
-	if encoded == "" {
-		return "", errMissingKey
-	}
-
-	decoded, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return "", fmt.Errorf("%w: %v", errDecodeKey, err)
-	}
-	return string(decoded), nil
-}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/markdown"
+)
+
+// maxBodyBytes bounds every JSON request body.
+const maxBodyBytes = 1 << 20
+
+// validator is implemented by request payloads that check their own fields
+// after decoding.
+type validator interface {
+	Validate() []apierror.FieldError
+}
+
+// decodeJSON reads r.Body into dst with a size limit and strict field
+// checking, then runs dst.Validate when available. Content-Type, when sent,
+// must be JSON. On failure it writes the error response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+			apierror.Write(w, r, apierror.UnsupportedMedia, map[string]any{"content_type": ct})
+			return false
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		writeDecodeError(w, r, err)
+		return false
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		apierror.Write(w, r, apierror.InvalidJSON, map[string]any{"reason": "body must contain a single JSON value"})
+		return false
+	}
+
+	if v, ok := dst.(validator); ok {
+		if fields := v.Validate(); len(fields) > 0 {
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": fields})
+			return false
+		}
+	}
+	return true
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		maxErr    *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		apierror.Write(w, r, apierror.BodyTooLarge, map[string]any{"limit_bytes": maxErr.Limit})
+	case errors.As(err, &syntaxErr):
+		apierror.Write(w, r, apierror.InvalidJSON, map[string]any{"offset": syntaxErr.Offset})
+	case errors.As(err, &typeErr):
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: typeErr.Field, Code: "wrong_type", Message: "expected " + typeErr.Type.String(),
+		}}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json bu hata için tip sunmuyor
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+			Field: field, Code: "unknown_field",
+		}}})
+	case errors.Is(err, io.EOF):
+		apierror.Write(w, r, apierror.InvalidJSON, map[string]any{"reason": "empty body"})
+	default:
+		apierror.Write(w, r, apierror.InvalidJSON, nil)
+	}
+}
+
+// requireField appends a "required" error when value is blank.
+func requireField(errs []apierror.FieldError, field, value string) []apierror.FieldError {
+	if strings.TrimSpace(value) == "" {
+		errs = append(errs, apierror.FieldError{Field: field, Code: "required"})
+	}
+	return errs
+}
+
+// tcField appends an "invalid" error when a non-blank value is not a valid
+// TC Kimlik No. Blank values are left to requireField.
+func tcField(errs []apierror.FieldError, field, value string) []apierror.FieldError {
+	if strings.TrimSpace(value) == "" {
+		return errs
+	}
+	if err := identity.Validate(identity.Normalize(value)); err != nil {
+		errs = append(errs, apierror.FieldError{Field: field, Code: "invalid", Message: err.Error()})
+	}
+	return errs
+}
+
+// markdownField appends an error when value is not an acceptable
+// announcement body (active HTML or disallowed link schemes).
+func markdownField(errs []apierror.FieldError, field, value string) []apierror.FieldError {
+	switch err := markdown.Validate(value); {
+	case errors.Is(err, markdown.ErrUnsafeHTML):
+		errs = append(errs, apierror.FieldError{Field: field, Code: "unsafe_html", Message: err.Error()})
+	case errors.Is(err, markdown.ErrUnsafeLink):
+		errs = append(errs, apierror.FieldError{Field: field, Code: "unsafe_link", Message: err.Error()})
+	}
+	return errs
+}
+
+// maxLenField appends a "too_long" error when value exceeds n runes.
+func maxLenField(errs []apierror.FieldError, field, value string, n int) []apierror.FieldError {
+	if len([]rune(value)) > n {
+		errs = append(errs, apierror.FieldError{Field: field, Code: "too_long"})
+	}
+	return errs
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/rbac"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /api/admin/roles
+func ListRoles(p *rbac.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"roles":       rbac.Roles,
+			"permissions": rbac.Permissions,
+			"matrix":      p.Matrix(),
+		})
+	}
+}
+
+type roleUpdateInput struct {
+	Permissions []string `json:"permissions"`
+}
+
+// PUT /api/admin/roles/{role}   body: {"permissions":["announcement.create", ...]}
+// Patron düzenlenemez; her zaman tüm yetkilere sahiptir.
+func (api *API) UpdateRole(p *rbac.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, ok := rbac.ParseRole(mux.Vars(r)["role"])
+		if !ok {
+			apierror.Write(w, r, apierror.NotFound, nil)
+			return
+		}
+		var in roleUpdateInput
+		if !decodeJSON(w, r, &in) {
+			return
+		}
+		if in.Permissions == nil {
+			in.Permissions = []string{}
+		}
+
+		before, err := p.Set(role, in.Permissions)
+		switch {
+		case errors.Is(err, rbac.ErrImmutableRole):
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+				Field: "role", Code: "invalid", Message: err.Error(),
+			}}})
+			return
+		case errors.Is(err, rbac.ErrUnknownPermission):
+			apierror.Write(w, r, apierror.ValidationFailed, map[string]any{"fields": []apierror.FieldError{{
+				Field: "permissions", Code: "invalid", Message: err.Error(),
+			}}})
+			return
+		case err != nil:
+			apierror.Write(w, r, apierror.StoreWriteFailed, nil)
+			return
+		}
+
+		after := p.Matrix()[role]
+		api.recordAudit(r, "roles.update", string(role), before, after)
+		writeJSON(w, http.StatusOK, map[string]any{"role": role, "permissions": after})
+	}
+}
+
+// roleChoices is the hint shown when an unknown role is submitted.
+func roleChoices() string {
+	names := make([]string, len(rbac.Roles))
+	for i, r := range rbac.Roles {
+		names[i] = string(r)
+	}
+	return strings.Join(names, " | ")
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/rbac"
+)
+
+// Personel ve puantaj uçları, çağıranın kapsamına göre satırları süzer:
+// own (yalnızca kendi kaydı), branch (kendi şubesi), region (allowlist'teki
+// şube listesi) ya da company (tüm şirket).
+
+type scopeLevel int
+
+const (
+	scopeOwn scopeLevel = iota
+	scopeBranch
+	scopeRegion
+	scopeCompany
+)
+
+var scopeNames = map[scopeLevel]string{
+	scopeOwn: "own", scopeBranch: "branch", scopeRegion: "region", scopeCompany: "company",
+}
+
+func (l scopeLevel) String() string { return scopeNames[l] }
+
+func parseScopeLevel(s string) (scopeLevel, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for l, name := range scopeNames {
+		if name == s {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// scopeArea names the permission triple checked for an endpoint.
+type scopeArea struct {
+	branch, region, all rbac.Permission
+}
+
+var (
+	attendanceArea = scopeArea{rbac.AttendanceViewBranch, rbac.AttendanceViewRegion, rbac.AttendanceViewAll}
+	personnelArea  = scopeArea{rbac.PersonnelViewBranch, rbac.PersonnelViewRegion, rbac.PersonnelViewAll}
+)
+
+type accessScope struct {
+	level    scopeLevel
+	tc       string
+	branches map[string]struct{} // branchKey ile
+}
+
+// allows reports whether row is visible to the caller.
+func (s accessScope) allows(row map[string]any) bool {
+	switch s.level {
+	case scopeCompany:
+		return true
+	case scopeOwn:
+		return s.tc != "" && rowTC(row) == s.tc
+	default:
+		_, ok := s.branches[branchKey(rowBranch(row))]
+		return ok
+	}
+}
+
+func (s accessScope) filter(rows []map[string]any) []map[string]any {
+	if s.level == scopeCompany {
+		return rows
+	}
+	out := make([]map[string]any, 0)
+	for _, row := range rows {
+		if s.allows(row) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// resolveScope works out what the caller may see. rows is the Enibra
+// personnel list, used to find the caller's own branch. On failure the
+// error response has been written and ok is false.
+func (api *API) resolveScope(w http.ResponseWriter, r *http.Request, area scopeArea, rows []map[string]any) (accessScope, bool) {
+	s, reason := api.scopeFor(actorTC(r), area, rows)
+	if reason != "" {
+		apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": reason})
+		return s, false
+	}
+	return s, true
+}
+
+// scopeFor is resolveScope for a known caller TC, without the error
+// response: reason is empty on success and says why the scope could not be
+// resolved otherwise. Rol istemci header'ından değil, allowlist kaydından
+// okunur; TC'siz çağrı hiçbir kapsam almaz.
+func (api *API) scopeFor(tc string, area scopeArea, rows []map[string]any) (accessScope, string) {
+	s := accessScope{level: scopeOwn, tc: tc}
+	if s.tc == "" {
+		return s, AuthHeader + " header required"
+	}
+
+	api.ensureLoaded()
+	entry := allowItem{Role: string(rbac.Personel)}
+	api.allowDB.RLock()
+	if it, ok := api.allowDB.ByTC[s.tc]; ok && it.activeAt(time.Now()) {
+		entry = it
+	}
+	api.allowDB.RUnlock()
+
+	if role, ok := rbac.ParseRole(entry.Role); ok {
+		p := api.Policy()
+		switch {
+		case p.Allows(role, area.all):
+			s.level = scopeCompany
+		case p.Allows(role, area.region):
+			s.level = scopeRegion
+		case p.Allows(role, area.branch):
+			s.level = scopeBranch
+		}
+	}
+	if l, ok := parseScopeLevel(entry.Scope); ok {
+		s.level = l
+	}
+	if s.level == scopeCompany {
+		return s, ""
+	}
+
+	s.branches = map[string]struct{}{}
+	if s.level == scopeRegion {
+		for _, b := range entry.Region {
+			if k := branchKey(b); k != "" {
+				s.branches[k] = struct{}{}
+			}
+		}
+		if len(s.branches) == 0 {
+			s.level = scopeBranch // bölge tanımlı değil: kendi şubesi
+		}
+	}
+	if s.level == scopeBranch {
+		branch := entry.Branch
+		if branch == "" {
+			for _, row := range rows {
+				if rowTC(row) == s.tc {
+					branch = rowBranch(row)
+					break
+				}
+			}
+		}
+		if branchKey(branch) == "" {
+			return s, "branch unknown"
+		}
+		s.branches[branchKey(branch)] = struct{}{}
+	}
+	return s, ""
+}
+
+// personnelRows extracts the record list from the shapes Enibra returns:
+// a bare array, {"items":[...]} or {"SONUC_MESAJI":[...]}.
+func personnelRows(body []byte) []map[string]any {
+	var rows []map[string]any
+	if err := json.Unmarshal(body, &rows); err == nil {
+		return rows
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(body, &obj) != nil {
+		return nil
+	}
+	for _, k := range []string{"SONUC_MESAJI", "items"} {
+		if raw, ok := obj[k]; ok && json.Unmarshal(raw, &rows) == nil {
+			return rows
+		}
+	}
+	return nil
+}
+
+// filterPersonnelBody applies s to body while keeping its outer shape.
+func filterPersonnelBody(body []byte, s accessScope) ([]byte, bool) {
+	var rows []map[string]any
+	if err := json.Unmarshal(body, &rows); err == nil {
+		b, err := json.Marshal(s.filter(rows))
+		return b, err == nil
+	}
+	var obj map[string]any
+	if json.Unmarshal(body, &obj) != nil {
+		return nil, false
+	}
+	for _, k := range []string{"SONUC_MESAJI", "items"} {
+		list, ok := obj[k].([]any)
+		if !ok {
+			continue
+		}
+		kept := make([]any, 0, len(list))
+		for _, it := range list {
+			if m, ok := it.(map[string]any); ok && s.allows(m) {
+				kept = append(kept, m)
+			}
+		}
+		obj[k] = kept
+		b, err := json.Marshal(obj)
+		return b, err == nil
+	}
+	return nil, false
+}
+
+func rowTC(row map[string]any) string {
+	return identity.Normalize(anyToString(firstNonEmpty(row, "TC_KIMLIK_NO", "TC", "TCKN", "TC_NO", "tc", "tckimlik")))
+}
+
+func rowBranch(row map[string]any) string {
+	return strings.TrimSpace(anyToString(firstNonEmpty(row, "SUBE", "GOREV_YERI", "ISYERI", "ISYERI_ADI", "sube")))
+}
+
+// konumTipi classifies the workplace from the branch and department names.
+func konumTipi(row map[string]any) string {
+	ham := branchKey(rowBranch(row) + " " + anyToString(firstNonEmpty(row, "ISYERI_TIPI", "BOLUM", "DEPARTMAN")))
+	switch {
+	case strings.Contains(ham, "genel") || strings.Contains(ham, "merkez") || strings.Contains(ham, "gm"):
+		return "GENEL_MERKEZ"
+	case strings.Contains(ham, "magaza") || strings.Contains(ham, "satis"):
+		return "MAGAZA"
+	}
+	return "BILINMIYOR"
+}
+
+func rowGorev(row map[string]any) string {
+	return strings.TrimSpace(anyToString(firstNonEmpty(row, "GOREV", "GOREVI", "GOREV_ADI", "POZISYON", "UNVAN", "gorev")))
+}
+
+// branchKey folds case and Turkish letters so "Kadıköy" == "KADIKÖY".
+func branchKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer("ğ", "g", "ü", "u", "ş", "s", "ı", "i", "ö", "o", "ç", "c", "i̇", "i").Replace(s)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AuthHeader carries the session token returned by POST /api/giris as
+// "Authorization: Bearer <token>".
+const AuthHeader = "Authorization"
+
+// sessionTokenTTL: oturum token'ı bu kadar geçerli; sonra yeniden giriş gerekir.
+// Rol her istekte allowlist'ten okunduğu için rol değişikliği beklemeden işler.
+const sessionTokenTTL = 12 * time.Hour
+
+const sessionTokenPurpose = "session"
+
+// issueSession returns a session token for tc and its expiry.
+func (api *API) issueSession(tc string, now time.Time) (string, time.Time, error) {
+	exp := now.Add(sessionTokenTTL)
+	token, err := api.sealToken(sessionTokenPurpose, tc, exp)
+	return token, exp, err
+}
+
+// Authenticate makes the session token the only source of the caller's
+// identity: X-TC is overwritten with the token's TC and removed when there is
+// no valid token. İstemcinin gönderdiği X-TC hiçbir durumda kimlik vermez;
+// audit, created_by ve allowlist rolleri yalnızca doğrulanmış TC'yi görür.
+// Must run before AllowlistRoles.
+func (api *API) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(ActorHeader)
+		if token, ok := bearerToken(r); ok {
+			if tc := api.openToken(sessionTokenPurpose, token, time.Now()); tc != "" {
+				r.Header.Set(ActorHeader, tc)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get(AuthHeader)), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
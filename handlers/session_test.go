@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hys-go-backend/config"
+)
+
+func TestAuthenticate(t *testing.T) {
+	api := New(&config.Config{DataDir: t.TempDir(), Blob: config.BlobConfig{URLSecret: "test secret"}})
+	now := time.Now()
+	session, _, err := api.issueSession("25031519376", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, _ := api.sealToken(streamTokenPurpose, "25031519376", now.Add(time.Minute))
+	expired, _ := api.sealToken(sessionTokenPurpose, "25031519376", now.Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		auth   string
+		xtc    string
+		wantTC string
+	}{
+		{"session token", "Bearer " + session, "", "25031519376"},
+		{"lower-case scheme", "bearer " + session, "", "25031519376"},
+		{"token wins over X-TC", "Bearer " + session, "10000000146", "25031519376"},
+		{"X-TC alone is ignored", "", "10000000146", ""},
+		{"stream token is not a session", "Bearer " + stream, "", ""},
+		{"expired token", "Bearer " + expired, "", ""},
+		{"garbage token", "Bearer abc", "10000000146", ""},
+		{"basic auth", "Basic " + session, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/announcements", nil)
+			if tt.auth != "" {
+				req.Header.Set(AuthHeader, tt.auth)
+			}
+			if tt.xtc != "" {
+				req.Header.Set(ActorHeader, tt.xtc)
+			}
+			var got string
+			api.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = actorTC(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.wantTC {
+				t.Errorf("actor = %q, want %q", got, tt.wantTC)
+			}
+		})
+	}
+}
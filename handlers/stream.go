@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/metrics"
+	"hys-go-backend/websocket"
+)
+
+const (
+	streamBuffer      = 256 // Last-Event-ID ile devam için saklanan son olaylar
+	streamClientQueue = 64  // dolarsa istemci kapatılır, yeniden bağlanıp devam eder
+	streamHeartbeat   = 25 * time.Second
+	streamRetry       = 3 * time.Second // SSE "retry:" önerisi
+)
+
+// streamTypes are the event type prefixes a client can ask for with
+// ?types=announcement,shift.
+var streamTypes = []string{"announcement", "shift", "personnel"}
+
+// streamEvent is one pushed event. IDs are consecutive; visible decides per
+// client whether it may see the event.
+type streamEvent struct {
+	ID      uint64
+	Type    string
+	Data    any
+	visible func(c *streamClient) bool
+}
+
+// streamHub fans events out to the connected clients and keeps the last
+// streamBuffer events for resuming clients. ID'ler açılış anının
+// milisaniyesinden başlar; yeniden başlatma sonrası eski ID ile gelen
+// istemci boşluğu fark edip "resync" alır.
+type streamHub struct {
+	mu      sync.Mutex
+	seq     uint64
+	ring    []streamEvent // eskiden yeniye
+	clients map[*streamClient]struct{}
+}
+
+var hub = &streamHub{seq: uint64(time.Now().UnixMilli()), clients: map[*streamClient]struct{}{}}
+
+func (h *streamHub) publish(typ string, data any, visible func(c *streamClient) bool) {
+	h.mu.Lock()
+	h.seq++
+	ev := streamEvent{ID: h.seq, Type: typ, Data: data, visible: visible}
+	if len(h.ring) == streamBuffer {
+		copy(h.ring, h.ring[1:])
+		h.ring[len(h.ring)-1] = ev
+	} else {
+		h.ring = append(h.ring, ev)
+	}
+	for c := range h.clients {
+		select {
+		case c.ch <- ev:
+		default:
+			c.lag()
+		}
+	}
+	h.mu.Unlock()
+	metrics.StreamEvents.Inc(typ)
+}
+
+// subscribe registers c and returns the buffered events after lastID. gap
+// is set when events after lastID are no longer buffered; the client then
+// has to reload through the REST endpoints.
+func (h *streamHub) subscribe(c *streamClient, lastID uint64, resume bool) (backlog []streamEvent, gap bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[c] = struct{}{}
+	if !resume {
+		return nil, false
+	}
+	oldest := h.seq - uint64(len(h.ring)) + 1
+	if lastID > h.seq || lastID+1 < oldest {
+		return nil, true
+	}
+	for _, ev := range h.ring {
+		if ev.ID > lastID {
+			backlog = append(backlog, ev)
+		}
+	}
+	return backlog, false
+}
+
+func (h *streamHub) unsubscribe(c *streamClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+func (h *streamHub) hasClients() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients) > 0
+}
+
+// streamClient is one connection. Scopes are resolved once at connect;
+// nil means the caller gets no events of that area.
+type streamClient struct {
+	tc    string
+	v     *viewer
+	types []string
+
+	attendance *accessScope
+	personnel  *accessScope
+
+	ch      chan streamEvent
+	lagged  chan struct{}
+	lagOnce sync.Once
+}
+
+func (api *API) newStreamClient(r *http.Request, tc string, types []string) *streamClient {
+	c := &streamClient{
+		tc: tc, v: api.viewerFor(r, tc), types: types,
+		ch: make(chan streamEvent, streamClientQueue), lagged: make(chan struct{}),
+	}
+	if c.wantsType("shift") || c.wantsType("personnel") {
+		rows, err := api.enibraPersonnel(r.Context())
+		if err != nil {
+			log.Printf("[WARN] stream: personnel lookup for scope: %v", err)
+		}
+		if s, reason := api.scopeFor(c.tc, attendanceArea, rows); reason == "" {
+			c.attendance = &s
+		}
+		if s, reason := api.scopeFor(c.tc, personnelArea, rows); reason == "" {
+			c.personnel = &s
+		}
+	}
+	return c
+}
+
+func (c *streamClient) lag() { c.lagOnce.Do(func() { close(c.lagged) }) }
+
+func (c *streamClient) wantsType(prefix string) bool {
+	if len(c.types) == 0 {
+		return true
+	}
+	for _, t := range c.types {
+		if t == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *streamClient) wants(ev streamEvent) bool {
+	typ, _, _ := strings.Cut(ev.Type, ".")
+	return c.wantsType(typ) && (ev.visible == nil || ev.visible(c))
+}
+
+// streamSink is the transport: SSE or WebSocket.
+type streamSink interface {
+	send(ev streamEvent) error
+	resync() error
+	heartbeat() error
+}
+
+// streamHeartbeatInterval keeps heartbeats well inside the server's
+// WriteTimeout, so even a connection whose deadline cannot be extended
+// sees traffic before it would be cut.
+func (api *API) streamHeartbeatInterval() time.Duration {
+	hb := streamHeartbeat
+	if wt := api.currentConfig().Server.WriteTimeout; wt > 0 && wt/2 < hb {
+		hb = wt / 2
+	}
+	if hb < time.Second {
+		hb = time.Second
+	}
+	return hb
+}
+
+// StopStreams ends every open stream: WebSocket clients get a going-away
+// close frame, SSE responses end. Register it with
+// http.Server.RegisterOnShutdown; Shutdown does not wait for hijacked
+// connections and would otherwise wait for SSE responses until its timeout.
+func (api *API) StopStreams() { api.stopStreams() }
+
+// runStream replays the backlog and then forwards events until ctx ends,
+// the client falls behind, a write fails or maxLife (when set) passes.
+func (api *API) runStream(ctx context.Context, c *streamClient, sink streamSink, transport string, lastID uint64, resume bool, maxLife time.Duration) {
+	backlog, gap := hub.subscribe(c, lastID, resume)
+	defer hub.unsubscribe(c)
+	metrics.StreamClients.Add(1, transport)
+	defer metrics.StreamClients.Add(-1, transport)
+
+	if gap {
+		if sink.resync() != nil {
+			return
+		}
+	}
+	for _, ev := range backlog {
+		if c.wants(ev) && sink.send(ev) != nil {
+			return
+		}
+	}
+
+	var expire <-chan time.Time
+	if maxLife > 0 {
+		t := time.NewTimer(maxLife)
+		defer t.Stop()
+		expire = t.C
+	}
+	tick := time.NewTicker(api.streamHeartbeatInterval())
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expire:
+			return // istemci Last-Event-ID ile yeniden bağlanır
+		case <-c.lagged:
+			metrics.StreamDropped.Inc(transport)
+			return
+		case ev := <-c.ch:
+			if c.wants(ev) && sink.send(ev) != nil {
+				return
+			}
+		case <-tick.C:
+			if sink.heartbeat() != nil {
+				return
+			}
+		}
+	}
+}
+
+// GET /api/stream
+// Canlı olay akışı: yeni duyurular, onay bildirimleri, vardiya devamsızlık
+// uyarıları ve personel değişiklikleri. Olaylar çağıranın duyuru hedef
+// kitlesine ve rol kapsamına göre süzülür.
+//
+//	Accept: text/event-stream        -> Server-Sent Events
+//	Upgrade: websocket               -> WebSocket, her olay bir JSON metin mesajı
+//	Last-Event-ID / ?last_event_id=  -> kaldığı yerden devam
+//	?types=announcement,shift,personnel
+//	?token=...                       -> POST /api/stream/token ile alınır
+//
+// Kimlik tarayıcıda akış token'ı, diğer istemcilerde oturum token'ı ile gelir.
+func (api *API) Stream(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if token := r.URL.Query().Get("token"); token != "" {
+		if tc = api.openToken(streamTokenPurpose, token, time.Now()); tc == "" {
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "invalid or expired stream token"})
+			return
+		}
+	}
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader, "param": "token"})
+		return
+	}
+	var types []string
+	if s := strings.TrimSpace(r.URL.Query().Get("types")); s != "" {
+		for _, t := range strings.Split(s, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !containsFold(streamTypes, t) {
+				apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"param": "types", "allowed": streamTypes})
+				return
+			}
+			types = append(types, t)
+		}
+	}
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("last_event_id")
+	}
+	lastID, err := strconv.ParseUint(strings.TrimSpace(last), 10, 64)
+	resume := err == nil
+
+	if websocket.IsUpgrade(r) {
+		api.serveStreamWS(w, r, api.newStreamClient(r, tc, types), lastID, resume)
+		return
+	}
+	api.serveStreamSSE(w, r, api.newStreamClient(r, tc, types), lastID, resume)
+}
+
+type sseSink struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	// extend pushes the write deadline past the next heartbeat; false when
+	// the writer does not support it.
+	extend func() bool
+}
+
+func (s *sseSink) write(format string, args ...any) error {
+	s.extend()
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseSink) send(ev streamEvent) error {
+	b, err := json.Marshal(ev.Data)
+	if err != nil {
+		log.Printf("[ERROR] stream: %s: %v", ev.Type, err)
+		return nil
+	}
+	return s.write("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, b)
+}
+
+func (s *sseSink) resync() error {
+	return s.write("event: resync\ndata: {}\n\n")
+}
+
+func (s *sseSink) heartbeat() error { return s.write(": ping\n\n") }
+
+func (api *API) serveStreamSSE(w http.ResponseWriter, r *http.Request, c *streamClient, lastID uint64, resume bool) {
+	rc := http.NewResponseController(w)
+	hb := api.streamHeartbeatInterval()
+	// ReadTimeout dolunca sunucunun arka plan okuması isteği iptal etmesin.
+	_ = rc.SetReadDeadline(time.Time{})
+	sink := &sseSink{w: w, rc: rc, extend: func() bool {
+		return rc.SetWriteDeadline(time.Now().Add(2*hb+5*time.Second)) == nil
+	}}
+	// Süre uzatılamıyorsa akış WriteTimeout dolmadan kapatılır; istemci
+	// retry süresi sonunda Last-Event-ID ile devam eder.
+	var maxLife time.Duration
+	if !sink.extend() {
+		maxLife = api.currentConfig().Server.WriteTimeout * 9 / 10
+	}
+
+	// Shutdown SSE bağlantısının boşalmasını beklemez; StopStreams akışı bitirir.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(api.streams, cancel)()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx tamponlamasın
+	w.WriteHeader(http.StatusOK)
+	if sink.write("retry: %d\n\n", streamRetry.Milliseconds()) != nil {
+		return
+	}
+	api.runStream(ctx, c, sink, "sse", lastID, resume, maxLife)
+}
+
+type wsSink struct {
+	conn *websocket.Conn
+	hb   time.Duration
+}
+
+type wsMessage struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+func (s *wsSink) deadline() time.Time { return time.Now().Add(s.hb + 5*time.Second) }
+
+func (s *wsSink) send(ev streamEvent) error {
+	b, err := json.Marshal(wsMessage{ID: strconv.FormatUint(ev.ID, 10), Type: ev.Type, Data: ev.Data})
+	if err != nil {
+		log.Printf("[ERROR] stream: %s: %v", ev.Type, err)
+		return nil
+	}
+	return s.conn.WriteText(b, s.deadline())
+}
+
+func (s *wsSink) resync() error {
+	return s.conn.WriteText([]byte(`{"type":"resync"}`), s.deadline())
+}
+
+func (s *wsSink) heartbeat() error { return s.conn.Ping(s.deadline()) }
+
+func (api *API) serveStreamWS(w http.ResponseWriter, r *http.Request, c *streamClient, lastID uint64, resume bool) {
+	conn, err := websocket.Upgrade(w, r, nil)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadVersion) {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+		}
+		apierror.Write(w, r, apierror.InvalidRequest, map[string]any{"reason": err.Error()})
+		return
+	}
+	hb := api.streamHeartbeatInterval()
+	// Devralınan bağlantı ne isteğin context'ine ne de Shutdown'a bağlı;
+	// kapanışta StopStreams ile biter.
+	ctx, cancel := context.WithCancel(api.streams)
+	defer cancel()
+
+	// Okuyucu: ping'lere pong döner, close'u yanıtlar. İstemciden iki
+	// heartbeat boyunca (pong dahil) hiçbir şey gelmezse bağlantı ölü sayılır.
+	conn.SetIdleTimeout(2*hb + 5*time.Second)
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	api.runStream(ctx, c, &wsSink{conn: conn, hb: hb}, "websocket", lastID, resume, 0)
+	_ = conn.Close(websocket.CloseGoingAway, "")
+}
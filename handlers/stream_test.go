@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hys-go-backend/config"
+	"hys-go-backend/websocket"
+)
+
+func newTestHub(published int) *streamHub {
+	h := &streamHub{seq: 1000, clients: map[*streamClient]struct{}{}}
+	for i := 0; i < published; i++ {
+		h.publish("announcement.published", i, nil)
+	}
+	return h
+}
+
+func newTestClient() *streamClient {
+	return &streamClient{ch: make(chan streamEvent, streamClientQueue), lagged: make(chan struct{})}
+}
+
+func TestStreamHubSubscribe(t *testing.T) {
+	tests := []struct {
+		name      string
+		published int
+		lastID    uint64
+		resume    bool
+		wantFirst uint64 // ilk backlog olayının ID'si; 0: backlog yok
+		wantLen   int
+		wantGap   bool
+	}{
+		{"no resume", 5, 0, false, 0, 0, false},
+		{"replay after lastID", 5, 1003, true, 1004, 2, false},
+		{"up to date", 5, 1005, true, 0, 0, false},
+		{"whole ring", 5, 1000, true, 1001, 5, false},
+		{"oldest still buffered", streamBuffer + 10, 1010, true, 1011, streamBuffer, false},
+		{"lastID beyond the ring", streamBuffer + 10, 1009, true, 0, 0, true},
+		{"lastID from the future", 5, 2000, true, 0, 0, true},
+		{"empty hub", 0, 1000, true, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHub(tt.published)
+			c := newTestClient()
+			backlog, gap := h.subscribe(c, tt.lastID, tt.resume)
+
+			if gap != tt.wantGap || len(backlog) != tt.wantLen {
+				t.Fatalf("got %d events, gap %v; want %d, gap %v", len(backlog), gap, tt.wantLen, tt.wantGap)
+			}
+			for i, ev := range backlog {
+				if want := tt.wantFirst + uint64(i); ev.ID != want {
+					t.Fatalf("backlog[%d].ID = %d, want %d", i, ev.ID, want)
+				}
+			}
+			if _, ok := h.clients[c]; !ok {
+				t.Error("client not registered")
+			}
+		})
+	}
+}
+
+func TestStreamHubDropsLaggingClient(t *testing.T) {
+	h := newTestHub(0)
+	slow, fast := newTestClient(), newTestClient()
+	h.subscribe(slow, 0, false)
+	h.subscribe(fast, 0, false)
+
+	for i := 0; i < streamClientQueue; i++ {
+		h.publish("shift.absence", i, nil)
+		<-fast.ch
+	}
+	select {
+	case <-slow.lagged:
+		t.Fatal("client dropped with a full but not overflowing queue")
+	default:
+	}
+
+	h.publish("shift.absence", "overflow", nil)
+	select {
+	case <-slow.lagged:
+	default:
+		t.Fatal("lagging client not dropped")
+	}
+	select {
+	case <-fast.lagged:
+		t.Fatal("client that kept up was dropped")
+	default:
+	}
+	h.publish("shift.absence", "again", nil) // ikinci kez kapatmak panik yaratmamalı
+}
+
+// recordSink collects what runStream writes.
+type recordSink struct{ ids []uint64 }
+
+func (s *recordSink) send(ev streamEvent) error { s.ids = append(s.ids, ev.ID); return nil }
+func (s *recordSink) resync() error             { s.ids = append(s.ids, 0); return nil }
+func (s *recordSink) heartbeat() error          { return nil }
+
+func TestRunStreamEndsWhenClientLags(t *testing.T) {
+	api := New(&config.Config{DataDir: t.TempDir()})
+	c := newTestClient()
+	c.lag()
+
+	done := make(chan struct{})
+	go func() {
+		api.runStream(context.Background(), c, &recordSink{}, "sse", 0, false, 0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runStream kept a lagging client")
+	}
+	if hub.hasClients() {
+		t.Error("lagging client still subscribed")
+	}
+}
+
+func TestRunStreamResync(t *testing.T) {
+	api := New(&config.Config{DataDir: t.TempDir()})
+	hub.publish("announcement.published", "x", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sink := &recordSink{}
+	api.runStream(ctx, newTestClient(), sink, "sse", 1, true, 0) // ID 1 çoktan halkadan çıktı
+	if len(sink.ids) != 1 || sink.ids[0] != 0 {
+		t.Errorf("sink got %v, want a single resync", sink.ids)
+	}
+}
+
+func TestStopStreamsClosesWebSocket(t *testing.T) {
+	api := New(&config.Config{DataDir: t.TempDir()})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.serveStreamWS(w, r, newTestClient(), 0, false)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake: %v, %v", resp, err)
+	}
+
+	api.StopStreams()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("no close frame after StopStreams: %v", err)
+	}
+	payload := make([]byte, hdr[1]&0x7F)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	if hdr[0]&0x0F != websocket.OpClose || binary.BigEndian.Uint16(payload) != websocket.CloseGoingAway {
+		t.Errorf("got opcode %d payload %v, want close %d", hdr[0]&0x0F, payload, websocket.CloseGoingAway)
+	}
+}
+
+func TestStopStreamsEndsSSE(t *testing.T) {
+	api := New(&config.Config{DataDir: t.TempDir()})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.serveStreamSSE(w, r, newTestClient(), 0, false)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	if line, _ := br.ReadString('\n'); !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("first line %q", line)
+	}
+
+	api.StopStreams()
+	done := make(chan error, 1)
+	go func() { _, err := io.ReadAll(br); done <- err }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("body ended with %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SSE response still open after StopStreams")
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"hys-go-backend/apierror"
+)
+
+// streamTokenTTL bounds how long a stream token can be used to connect.
+// Token yalnızca bağlantı kurulurken kontrol edilir; açık akış süre dolunca
+// kesilmez, yeniden bağlanmak için yeni token alınır.
+const streamTokenTTL = 5 * time.Minute
+
+const streamTokenPurpose = "stream"
+
+// POST /api/stream/token
+// Tarayıcıdaki EventSource ve WebSocket özel header gönderemez. Panel oturum
+// token'ıyla kısa ömürlü bir akış token'ı alır ve /api/stream?token=... ile
+// bağlanır. Token TC'yi şifreli taşır; sorgu dizesinde TC açıkça görünmez.
+func (api *API) IssueStreamToken(w http.ResponseWriter, r *http.Request) {
+	tc := actorTC(r)
+	if tc == "" {
+		apierror.Write(w, r, apierror.MissingTC, map[string]any{"header": AuthHeader})
+		return
+	}
+	exp := time.Now().Add(streamTokenTTL)
+	token, err := api.sealToken(streamTokenPurpose, tc, exp)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal, nil)
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"token":      token,
+		"expires_at": exp.UTC().Format(time.RFC3339),
+	})
+}
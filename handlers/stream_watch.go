@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"hys-go-backend/metrics"
+)
+
+const (
+	streamPollInterval = time.Minute
+	// streamAbsenceGrace matches the default grace_min of
+	// /api/enibra/vardiya-uyarilari.
+	streamAbsenceGrace = 20 * time.Minute
+	// streamAbsenceWindow: vardiya başlangıcı + tolerans bundan eskiyse uyarı
+	// gönderilmez; sonradan bağlanan istemci günün tüm eski uyarılarını almasın.
+	streamAbsenceWindow = time.Hour
+)
+
+// StartStream connects the live stream to its sources: announcement and
+// review hooks, and a worker that polls Enibra for personnel changes and
+// missed shift starts while at least one client is connected.
+func (api *API) StartStream(ctx context.Context) {
+	onAnnouncementPublished(func(a Announcement) {
+		a.History, a.Workflow = nil, nil
+		hub.publish("announcement.published", api.present(a, time.Now()), func(c *streamClient) bool {
+			return c.v.canSee(a)
+		})
+	})
+	onWorkflowNotice(func(n workflowNotice) {
+		data := map[string]any{"announcement": api.present(n.Announcement, time.Now()), "step": n.Step}
+		hub.publish("announcement.review", data, func(c *streamClient) bool {
+			return containsFold(n.Recipients, c.tc)
+		})
+	})
+
+	RegisterWorker("stream_watcher", streamPollInterval)
+	go func() {
+		sw := &streamWatcher{api: api}
+		t := time.NewTicker(streamPollInterval)
+		defer t.Stop()
+		for {
+			if hub.hasClients() {
+				start := time.Now()
+				err := sw.poll(ctx, start)
+				metrics.ObserveJob("stream_watch", start, err)
+				if err != nil {
+					log.Printf("[WARN] stream watcher: %v", err)
+				}
+			} else {
+				sw.prev = nil // kimse dinlemiyorken kaçan değişiklikler toplu olay üretmesin
+			}
+			WorkerHeartbeat("stream_watcher")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// personSnap is the part of a personnel record whose changes are pushed.
+type personSnap struct {
+	fields map[string]string
+	row    map[string]any
+}
+
+func snapPerson(row map[string]any) personSnap {
+	return personSnap{row: row, fields: map[string]string{
+		"ad":         anyToString(firstNonEmpty(row, "ADI", "AD", "ad")),
+		"soyad":      anyToString(firstNonEmpty(row, "SOYADI", "SOYAD", "soyad")),
+		"sube":       rowBranch(row),
+		"gorev":      rowGorev(row),
+		"konum_tipi": konumTipi(row),
+	}}
+}
+
+type streamWatcher struct {
+	api     *API
+	prev    map[string]personSnap // TC -> son görülen kayıt; nil: henüz taban yok
+	day     string
+	alerted map[string]bool // TC|HH:MM, gün değişince sıfırlanır
+}
+
+func (sw *streamWatcher) poll(ctx context.Context, now time.Time) error {
+	rows, err := sw.api.enibraPersonnel(ctx)
+	if err != nil {
+		return err
+	}
+	sw.diffPersonnel(rows)
+	sw.checkAbsences(rows, now.In(time.Local))
+	return nil
+}
+
+// diffPersonnel publishes personnel.added, .changed and .removed against
+// the previous poll. İlk tur yalnızca tabanı kurar.
+func (sw *streamWatcher) diffPersonnel(rows []map[string]any) {
+	next := make(map[string]personSnap, len(rows))
+	for _, row := range rows {
+		if tc := rowTC(row); tc != "" {
+			next[tc] = snapPerson(row)
+		}
+	}
+	prev := sw.prev
+	sw.prev = next
+	if prev == nil {
+		return
+	}
+
+	for _, tc := range sortedSnapKeys(next) {
+		cur := next[tc]
+		old, ok := prev[tc]
+		if !ok {
+			publishPersonnel("personnel.added", tc, cur, nil, cur.row)
+			continue
+		}
+		changes := map[string]any{}
+		for k, v := range cur.fields {
+			if old.fields[k] != v {
+				changes[k] = map[string]string{"from": old.fields[k], "to": v}
+			}
+		}
+		if len(changes) > 0 {
+			// Şube değiştiyse eski ve yeni şubenin yetkilileri görür.
+			publishPersonnel("personnel.changed", tc, cur, changes, cur.row, old.row)
+		}
+	}
+	for _, tc := range sortedSnapKeys(prev) {
+		if _, ok := next[tc]; !ok {
+			publishPersonnel("personnel.removed", tc, prev[tc], nil, prev[tc].row)
+		}
+	}
+}
+
+func publishPersonnel(typ, tc string, s personSnap, changes map[string]any, rows ...map[string]any) {
+	data := map[string]any{"tc": tc}
+	for k, v := range s.fields {
+		data[k] = v
+	}
+	if changes != nil {
+		data["changes"] = changes
+	}
+	hub.publish(typ, data, func(c *streamClient) bool {
+		if c.personnel == nil {
+			return false
+		}
+		for _, row := range rows {
+			if c.personnel.allows(row) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// checkAbsences publishes shift.absence once per person and shift start
+// when the grace period has passed without a clock-in.
+func (sw *streamWatcher) checkAbsences(rows []map[string]any, now time.Time) {
+	if day := now.Format("2006-01-02"); day != sw.day {
+		sw.day, sw.alerted = day, map[string]bool{}
+	}
+	for _, row := range rows {
+		hour, minute, ok := shiftStart(row)
+		if !ok || clockedIn(row) {
+			continue
+		}
+		due := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location()).Add(streamAbsenceGrace)
+		if now.Before(due) || now.Sub(due) > streamAbsenceWindow {
+			continue
+		}
+		key := fmt.Sprintf("%s|%02d:%02d", rowTC(row), hour, minute)
+		if sw.alerted[key] {
+			continue
+		}
+		sw.alerted[key] = true
+
+		item := absenceItem(row)
+		item["sube"] = rowBranch(row)
+		item["check_time"] = now.Format(time.RFC3339)
+		item["grace_minutes"] = int(streamAbsenceGrace / time.Minute)
+		row := row
+		hub.publish("shift.absence", item, func(c *streamClient) bool {
+			return c.attendance != nil && c.attendance.allows(row)
+		})
+	}
+}
+
+func sortedSnapKeys(m map[string]personSnap) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
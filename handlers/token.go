@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"hys-go-backend/identity"
+)
+
+// sealToken encrypts tc and exp with AES-GCM, so the token is both opaque
+// and tamper-proof. purpose ("session", "stream") selects the key: bir
+// amaç için verilen token diğerinde açılmaz.
+func (api *API) sealToken(purpose, tc string, exp time.Time) (string, error) {
+	aead, err := api.tokenAEAD(purpose)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	plain := tc + "\n" + strconv.FormatInt(exp.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// openToken returns the TC in a token sealed for purpose, or "" when the
+// token is malformed, forged, sealed for another purpose or expired.
+func (api *API) openToken(purpose, token string, now time.Time) string {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return ""
+	}
+	aead, err := api.tokenAEAD(purpose)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return ""
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return ""
+	}
+	tc, e, ok := strings.Cut(string(plain), "\n")
+	exp, err := strconv.ParseInt(e, 10, 64)
+	if !ok || err != nil || now.Unix() > exp || identity.Validate(tc) != nil {
+		return ""
+	}
+	return tc
+}
+
+// tokenAEAD derives its key from the signed-link secret
+// (ATTACHMENT_URL_SECRET); ayrı bir anahtar türetildiği için ek imzalarıyla
+// karışmaz. Sır tanımlı değilse tokenlar yeniden başlatmada geçersiz olur.
+func (api *API) tokenAEAD(purpose string) (cipher.AEAD, error) {
+	key := sha256.Sum256(append([]byte("hys "+purpose+" token\n"), api.attachmentSecret()...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
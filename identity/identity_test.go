@@ -0,0 +1,82 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   string
+		want error
+	}{
+		{"valid", "25031519376", nil},
+		{"valid other", "10000000146", nil},
+		{"valid all ones", "11111111110", nil},
+		{"empty", "", ErrEmpty},
+		{"too short", "2503151937", ErrLength},
+		{"too long", "250315193760", ErrLength},
+		{"non-digit", "2503151937a", ErrLength},
+		{"inner space", "25031 19376", ErrLength},
+		{"leading zero", "02503151937", ErrLeadingZero},
+		{"wrong digit 10", "25031519366", ErrChecksum},
+		{"wrong digit 11", "25031519377", ErrChecksum},
+		{"swapped digits", "52031519376", ErrChecksum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.tc); !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.tc, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"25031519376", "25031519376"},
+		{" 25031519376\n", "25031519376"},
+		{"250 315 193 76", "25031519376"},
+		{"\t250-315-193-76 ", "25031519376"},
+		{"٢٥٠", ""}, // yalnızca ASCII rakamlar
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"25031519376", true},
+		{" 250 315 193 76 ", true},
+		{"25031519377", false},
+		{"0250315193 7", false},
+		{"abc", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.in); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskAndRedact(t *testing.T) {
+	if got := Mask("25031519376"); got != "250******76" {
+		t.Errorf("Mask = %q", got)
+	}
+	if got := Mask("123"); got != "***********" {
+		t.Errorf("Mask(short) = %q", got)
+	}
+	if got := Redact("/api/admin/allowlist/25031519376"); got != "/api/admin/allowlist/250******76" {
+		t.Errorf("Redact = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Package logging adds level filtering on top of the standard logger.
+//
+// The codebase logs with log.Printf("[INFO] ..."), "[WARN]", "[ERROR]" and
+// "[DEBUG]" tags; Install wraps the log output so lines below the active
+// level are dropped. Untagged lines are always written.
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	LevelDebug int32 = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var (
+	level atomic.Int32
+	tags  = []struct {
+		tag   []byte
+		level int32
+	}{
+		{[]byte("[DEBUG]"), LevelDebug},
+		{[]byte("[INFO]"), LevelInfo},
+		{[]byte("[WARN]"), LevelWarn},
+		{[]byte("[ERROR]"), LevelError},
+		{[]byte("[FATAL]"), LevelError},
+	}
+)
+
+func init() { level.Store(LevelInfo) }
+
+// Install routes the standard logger through the level filter.
+func Install(out io.Writer, lvl string) {
+	SetLevel(lvl)
+	log.SetOutput(&filter{out: out})
+}
+
+// SetLevel changes the active level; unknown names fall back to info.
+func SetLevel(lvl string) {
+	level.Store(Parse(lvl))
+}
+
+// Parse maps "debug", "info", "warn" and "error" to a level.
+func Parse(lvl string) int32 {
+	switch strings.ToLower(strings.TrimSpace(lvl)) {
+	case "debug":
+		return LevelDebug
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
+type filter struct {
+	out io.Writer
+}
+
+// log.Logger calls Write once per entry, so each p is a single line with
+// the timestamp prefix; the tag is searched in the first bytes only.
+func (f *filter) Write(p []byte) (int, error) {
+	head := p
+	if len(head) > 48 {
+		head = head[:48]
+	}
+	for _, t := range tags {
+		if bytes.Contains(head, t.tag) {
+			if t.level < level.Load() {
+				return len(p), nil
+			}
+			break
+		}
+	}
+	return f.out.Write(p)
+}
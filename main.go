@@ -1,54 +1,122 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"hys-go-backend/config"
+	"hys-go-backend/handlers"
+	"hys-go-backend/logging"
 	"hys-go-backend/routes"
+	"hys-go-backend/servertls"
 )
 
 func main() {
-	loadEnvFile(".env")
-	initTimeZone()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML/TOML config file")
+	flag.Parse()
 
-	port := strings.TrimSpace(os.Getenv("PORT"))
-	if port == "" {
-		port = "9090"
+	opts := config.Options{EnvFile: ".env", File: strings.TrimSpace(*configFile)}
+	cfg, err := config.Load(opts)
+	if err == nil {
+		err = cfg.EnsureDirs()
 	}
-	addr := "127.0.0.1:" + port
+	if err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	logging.Install(os.Stderr, cfg.LogLevel)
+	log.Printf("[INFO] effective configuration:")
+	for _, line := range cfg.Report() {
+		log.Printf("[INFO]   %s", line)
+	}
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		log.Printf("[WARN] CORS_ALLOWED_ORIGINS is empty: browsers on other origins are refused")
+	}
+	initTimeZone(cfg.TimeZone)
 
-	router := routes.NewRouter()
+	app := handlers.New(cfg)
+	router := routes.NewRouter(cfg, app)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	app.StartAllowlistSweeper(workersCtx)
+	app.StartAnnouncementScheduler(workersCtx)
+	app.StartStream(workersCtx)
 
 	server := &http.Server{
-		Addr:         addr,
+		Addr:         cfg.ListenAddr,
 		Handler:      router,
-		ReadTimeout:  20 * time.Second,
-		WriteTimeout: 20 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	server.RegisterOnShutdown(app.StopStreams)
+
+	var redirect *http.Server
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
 
-	go func() {
-		log.Printf("[INFO] server listening on http://%s", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[ERROR] listen: %v", err)
+	if cfg.TLS.Enabled {
+		reloader, err := servertls.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			log.Fatalf("[FATAL] tls: %v", err)
 		}
-	}()
+		go reloader.Watch(watchCtx, cfg.TLS.ReloadInterval)
+		server.TLSConfig = reloader.ServerConfig(cfg.TLS.MinVersion)
+
+		go func() {
+			log.Printf("[INFO] server listening on https://%s", cfg.ListenAddr)
+			if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Printf("[ERROR] listen: %v", err)
+			}
+		}()
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+		if cfg.TLS.RedirectAddr != "" {
+			redirect = &http.Server{
+				Addr:         cfg.TLS.RedirectAddr,
+				Handler:      servertls.RedirectHandler(cfg.ListenAddr),
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+			}
+			go func() {
+				log.Printf("[INFO] redirecting http://%s to https", cfg.TLS.RedirectAddr)
+				if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Printf("[ERROR] redirect listen: %v", err)
+				}
+			}()
+		}
+	} else {
+		go func() {
+			log.Printf("[INFO] server listening on http://%s", cfg.ListenAddr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("[ERROR] listen: %v", err)
+			}
+		}()
+	}
 
-	<-stop
-	log.Printf("[INFO] shutdown signal received")
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	for sig := range sigs {
+		if sig != syscall.SIGHUP {
+			log.Printf("[INFO] shutdown signal received (%s)", sig)
+			break
+		}
+		cfg = reload(app, cfg, opts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	if redirect != nil {
+		_ = redirect.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("[ERROR] graceful shutdown failed: %v", err)
 	} else {
@@ -56,40 +124,44 @@ func main() {
 	}
 }
 
-func loadEnvFile(path string) {
-	f, err := os.Open(path)
+// reload re-reads .env and the config file and applies the reloadable
+// settings. An invalid configuration is logged and the current one is kept.
+func reload(app *handlers.API, current *config.Config, opts config.Options) *config.Config {
+	log.Printf("[INFO] SIGHUP received, reloading configuration")
+	next, err := config.Load(opts)
 	if err != nil {
-		return
+		log.Printf("[ERROR] reload rejected: %v", err)
+		return current
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		if idx := strings.Index(line, "="); idx >= 0 {
-			key := strings.TrimSpace(line[:idx])
-			val := strings.TrimSpace(line[idx+1:])
-			if key == "" {
-				continue
-			}
-			if strings.HasPrefix(val, "\"") && strings.HasSuffix(val, "\"") && len(val) >= 2 {
-				val = strings.Trim(val, "\"")
-			}
-			if strings.HasPrefix(val, "'") && strings.HasSuffix(val, "'") && len(val) >= 2 {
-				val = strings.Trim(val, "'")
-			}
-			if os.Getenv(key) == "" {
-				_ = os.Setenv(key, val)
-			}
+	merged, ignored := config.Merge(current, next)
+	if err := merged.EnsureDirs(); err != nil {
+		log.Printf("[ERROR] reload rejected: %v", err)
+		return current
+	}
+	changes := config.Diff(current, merged)
+	if len(changes) == 0 && len(ignored) == 0 {
+		log.Printf("[INFO] reload: no changes")
+		return current
+	}
+	if err := routes.Reload(app, merged); err != nil {
+		log.Printf("[ERROR] reload rejected: %v", err)
+		for _, c := range changes {
+			log.Printf("[ERROR]   %s", c)
 		}
+		return current
+	}
+	logging.SetLevel(merged.LogLevel)
+	for _, c := range changes {
+		log.Printf("[INFO] reload: %s", c)
+	}
+	for _, k := range ignored {
+		log.Printf("[WARN] reload: %s changed but requires a restart", k)
 	}
+	return merged
 }
 
-func initTimeZone() {
-	tz := strings.TrimSpace(os.Getenv("TZ"))
+func initTimeZone(tz string) {
 	if tz == "" {
 		return
 	}
@@ -0,0 +1,464 @@
+// Package markdown renders the restricted Markdown dialect used in
+// announcement bodies to sanitized HTML and to plain text.
+//
+// Supported syntax:
+//
+//	# Başlık, ## Alt başlık, ### ...     (h2–h4; h1 istemcideki duyuru başlığıdır)
+//	- madde / * madde                      madde listesi
+//	1. madde                               numaralı liste
+//	> alıntı
+//	---                                    yatay çizgi
+//	**kalın**, *italik*, _italik_, `kod`
+//	[metin](https://...), https://...      bağlantı (http, https, mailto, tel)
+//
+// Raw HTML is never passed through; it is escaped and shows up as text.
+// Validate additionally rejects bodies that try to embed active content.
+package markdown
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnsafeHTML = errors.New("script, iframe, object, embed, style and form tags are not allowed")
+	ErrUnsafeLink = errors.New("links must use http, https, mailto or tel")
+)
+
+var unsafeTag = regexp.MustCompile(`(?i)<\s*/?\s*(script|iframe|frame|frameset|object|embed|applet|style|form|meta|link|base|svg|math)\b`)
+
+// Validate reports whether src may be stored: no active HTML and only
+// allowed link schemes.
+func Validate(src string) error {
+	if unsafeTag.MatchString(src) {
+		return ErrUnsafeHTML
+	}
+	for _, b := range parseBlocks(src) {
+		for _, line := range b.lines {
+			if !linksSafe(parseInline(line)) {
+				return ErrUnsafeLink
+			}
+		}
+	}
+	return nil
+}
+
+// HTML renders src as sanitized HTML. Unsafe links are rendered as their
+// text only.
+func HTML(src string) string {
+	var sb strings.Builder
+	for i, b := range parseBlocks(src) {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		switch b.kind {
+		case headingBlock:
+			tag := "h" + strconv.Itoa(b.level+1)
+			sb.WriteString("<" + tag + ">")
+			writeHTML(&sb, parseInline(b.lines[0]))
+			sb.WriteString("</" + tag + ">")
+		case ruleBlock:
+			sb.WriteString("<hr>")
+		case listBlock:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			sb.WriteString("<" + tag)
+			if b.ordered && b.start != 1 {
+				sb.WriteString(` start="` + strconv.Itoa(b.start) + `"`)
+			}
+			sb.WriteString(">")
+			for _, item := range b.lines {
+				sb.WriteString("<li>")
+				writeHTML(&sb, parseInline(item))
+				sb.WriteString("</li>")
+			}
+			sb.WriteString("</" + tag + ">")
+		case quoteBlock, paragraphBlock:
+			if b.kind == quoteBlock {
+				sb.WriteString("<blockquote>")
+			}
+			sb.WriteString("<p>")
+			for j, line := range b.lines {
+				if j > 0 {
+					sb.WriteString("<br>\n")
+				}
+				writeHTML(&sb, parseInline(line))
+			}
+			sb.WriteString("</p>")
+			if b.kind == quoteBlock {
+				sb.WriteString("</blockquote>")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Text renders src as plain text without markup, e.g. for push
+// notification previews.
+func Text(src string) string {
+	var parts []string
+	for _, b := range parseBlocks(src) {
+		var sb strings.Builder
+		switch b.kind {
+		case ruleBlock:
+			continue
+		case listBlock:
+			for j, item := range b.lines {
+				if j > 0 {
+					sb.WriteByte('\n')
+				}
+				if b.ordered {
+					sb.WriteString(strconv.Itoa(b.start+j) + ". ")
+				} else {
+					sb.WriteString("• ")
+				}
+				writeText(&sb, parseInline(item))
+			}
+		default:
+			for j, line := range b.lines {
+				if j > 0 {
+					sb.WriteByte('\n')
+				}
+				writeText(&sb, parseInline(line))
+			}
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// --- Blocks ---
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	listBlock
+	quoteBlock
+	ruleBlock
+)
+
+type block struct {
+	kind    blockKind
+	level   int // heading
+	ordered bool
+	start   int
+	lines   []string // paragraph/quote lines, list items, heading text
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,3})\s+(.*?)\s*#*\s*$`)
+	ruleRe    = regexp.MustCompile(`^(?:-\s*){3,}$|^(?:\*\s*){3,}$|^(?:_\s*){3,}$`)
+	bulletRe  = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedRe = regexp.MustCompile(`^(\d{1,9})[.)]\s+(.*)$`)
+	quoteRe   = regexp.MustCompile(`^>\s?(.*)$`)
+)
+
+func parseBlocks(src string) []block {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\r", "\n")
+	var (
+		out []block
+		cur *block
+	)
+	flush := func() {
+		if cur != nil {
+			out = append(out, *cur)
+			cur = nil
+		}
+	}
+	for _, raw := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(raw)
+		indented := len(raw) > 0 && (raw[0] == ' ' || raw[0] == '\t')
+		switch {
+		case line == "":
+			flush()
+		case ruleRe.MatchString(line):
+			flush()
+			out = append(out, block{kind: ruleBlock})
+		case headingRe.MatchString(line):
+			flush()
+			m := headingRe.FindStringSubmatch(line)
+			out = append(out, block{kind: headingBlock, level: len(m[1]), lines: []string{m[2]}})
+		case bulletRe.MatchString(line):
+			m := bulletRe.FindStringSubmatch(line)
+			if cur == nil || cur.kind != listBlock || cur.ordered {
+				flush()
+				cur = &block{kind: listBlock}
+			}
+			cur.lines = append(cur.lines, m[1])
+		case orderedRe.MatchString(line):
+			m := orderedRe.FindStringSubmatch(line)
+			if cur == nil || cur.kind != listBlock || !cur.ordered {
+				flush()
+				n, _ := strconv.Atoi(m[1])
+				cur = &block{kind: listBlock, ordered: true, start: n}
+			}
+			cur.lines = append(cur.lines, m[2])
+		case quoteRe.MatchString(line):
+			m := quoteRe.FindStringSubmatch(line)
+			if cur == nil || cur.kind != quoteBlock {
+				flush()
+				cur = &block{kind: quoteBlock}
+			}
+			cur.lines = append(cur.lines, m[1])
+		case cur != nil && cur.kind == listBlock && indented:
+			// madde devamı
+			cur.lines[len(cur.lines)-1] += " " + line
+		default:
+			if cur == nil || cur.kind != paragraphBlock {
+				flush()
+				cur = &block{kind: paragraphBlock}
+			}
+			cur.lines = append(cur.lines, line)
+		}
+	}
+	flush()
+	return out
+}
+
+// --- Inline ---
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	codeNode
+	strongNode
+	emNode
+	linkNode
+)
+
+type node struct {
+	kind nodeKind
+	text string // text, code
+	href string // link
+	kids []node // strong, em, link
+}
+
+// parseInline parses one line. Emphasis does not span lines.
+func parseInline(s string) []node {
+	var (
+		out []node
+		buf strings.Builder
+	)
+	flush := func() {
+		if buf.Len() > 0 {
+			out = append(out, node{kind: textNode, text: buf.String()})
+			buf.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			buf.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j >= 0 {
+				flush()
+				out = append(out, node{kind: codeNode, text: s[i+1 : i+1+j]})
+				i += j + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"):
+			if j := strings.Index(s[i+2:], "**"); j > 0 && s[i+2] != ' ' && s[i+1+j] != ' ' {
+				// "***" gibi bir dizide son iki yıldız kapatır; öncesi
+				// içerideki italiği kapatır: **a *b*** -> <strong>a <em>b</em></strong>
+				for i+2+j+2 < len(s) && s[i+2+j+2] == '*' {
+					j++
+				}
+				flush()
+				out = append(out, node{kind: strongNode, kids: parseInline(s[i+2 : i+2+j])})
+				i += j + 4
+				continue
+			}
+		case c == '*' || (c == '_' && !wordBefore(s, i)):
+			if j := closingEmphasis(s, i); j > 0 {
+				flush()
+				out = append(out, node{kind: emNode, kids: parseInline(s[i+1 : j])})
+				i = j + 1
+				continue
+			}
+		case c == '[':
+			if text, href, n, ok := parseLink(s[i:]); ok {
+				flush()
+				out = append(out, node{kind: linkNode, href: href, kids: parseInline(text)})
+				i += n
+				continue
+			}
+		case c == 'h' && !wordBefore(s, i):
+			if n := autolinkLen(s[i:]); n > 0 {
+				flush()
+				u := s[i : i+n]
+				out = append(out, node{kind: linkNode, href: u, kids: []node{{kind: textNode, text: u}}})
+				i += n
+				continue
+			}
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+	return out
+}
+
+// closingEmphasis finds the delimiter closing s[open] ('*' or '_').
+func closingEmphasis(s string, open int) int {
+	c := s[open]
+	if open+1 >= len(s) || s[open+1] == ' ' || s[open+1] == c {
+		return -1
+	}
+	for j := open + 2; j < len(s); j++ {
+		if c == '*' && s[j] == '*' && j+1 < len(s) && s[j+1] == '*' {
+			j++ // "**" içerideki kalını açar ya da kapatır; italiği kapatmaz
+			continue
+		}
+		if s[j] != c || s[j-1] == ' ' || s[j-1] == '\\' {
+			continue
+		}
+		if c == '_' && j+1 < len(s) && isWordByte(s, j+1) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// parseLink parses "[text](href)" at the start of s.
+func parseLink(s string) (text, href string, n int, ok bool) {
+	k := strings.IndexByte(s, ']')
+	if k < 1 || k+1 >= len(s) || s[k+1] != '(' {
+		return "", "", 0, false
+	}
+	m := strings.IndexByte(s[k+2:], ')')
+	if m < 0 {
+		return "", "", 0, false
+	}
+	href = strings.TrimSpace(s[k+2 : k+2+m])
+	if href == "" || strings.ContainsAny(href, " \t") {
+		return "", "", 0, false
+	}
+	return s[1:k], href, k + 3 + m, true
+}
+
+// autolinkLen returns the length of a bare http(s) URL at the start of s,
+// without trailing punctuation.
+func autolinkLen(s string) int {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return 0
+	}
+	n := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '<' || r == '>' || r == '"' })
+	if n < 0 {
+		n = len(s)
+	}
+	for n > 0 && strings.ContainsRune(".,;:!?)'", rune(s[n-1])) {
+		n--
+	}
+	if n <= len("https://") {
+		return 0
+	}
+	return n
+}
+
+func safeURL(href string) bool {
+	for _, r := range href {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto", "tel":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+func linksSafe(nodes []node) bool {
+	for _, n := range nodes {
+		if n.kind == linkNode && !safeURL(n.href) {
+			return false
+		}
+		if !linksSafe(n.kids) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeHTML(sb *strings.Builder, nodes []node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			sb.WriteString(html.EscapeString(n.text))
+		case codeNode:
+			sb.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case strongNode:
+			sb.WriteString("<strong>")
+			writeHTML(sb, n.kids)
+			sb.WriteString("</strong>")
+		case emNode:
+			sb.WriteString("<em>")
+			writeHTML(sb, n.kids)
+			sb.WriteString("</em>")
+		case linkNode:
+			if !safeURL(n.href) {
+				writeHTML(sb, n.kids)
+				continue
+			}
+			sb.WriteString(`<a href="` + html.EscapeString(n.href) + `" rel="noopener noreferrer nofollow" target="_blank">`)
+			writeHTML(sb, n.kids)
+			sb.WriteString("</a>")
+		}
+	}
+}
+
+func writeText(sb *strings.Builder, nodes []node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode, codeNode:
+			sb.WriteString(n.text)
+		case strongNode, emNode:
+			writeText(sb, n.kids)
+		case linkNode:
+			var label strings.Builder
+			writeText(&label, n.kids)
+			sb.WriteString(label.String())
+			if label.String() != n.href && safeURL(n.href) {
+				sb.WriteString(" (" + strings.TrimPrefix(n.href, "mailto:") + ")")
+			}
+		}
+	}
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("`*_[]()#+-.!>\\~|", c) >= 0
+}
+
+func wordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordByte(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,181 @@
+package markdown
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const rel = ` rel="noopener noreferrer nofollow" target="_blank"`
+
+func TestHTMLEscapesRawHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"img onerror", `<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>"},
+		{"html inside emphasis", "**<b>kalın</b>**", "<p><strong>&lt;b&gt;kalın&lt;/b&gt;</strong></p>"},
+		{"html inside code", "`<script>`", "<p><code>&lt;script&gt;</code></p>"},
+		{"html in heading", "## <i>x</i>", "<h3>&lt;i&gt;x&lt;/i&gt;</h3>"},
+		{"html in list item", "- <u>a</u>", "<ul><li>&lt;u&gt;a&lt;/u&gt;</li></ul>"},
+		{"entity stays literal", "&lt;script&gt;", "<p>&amp;lt;script&amp;gt;</p>"},
+		{"quote in link text", `[a"b](https://a.example.com)`, `<p><a href="https://a.example.com"` + rel + `>a&#34;b</a></p>`},
+		{"attribute breakout in href", `[x](https://a.example.com/"onmouseover="alert(1))`, `<p><a href="https://a.example.com/&#34;onmouseover=&#34;alert(1"` + rel + `>x</a>)</p>`},
+		{"autolink stops at quote", `https://a.example.com/"onmouseover=alert(1)`, `<p><a href="https://a.example.com/"` + rel + `>https://a.example.com/</a>&#34;onmouseover=alert(1)</p>`},
+		{"autolink stops at tag", "https://a.example.com/<script>", `<p><a href="https://a.example.com/"` + rel + `>https://a.example.com/</a>&lt;script&gt;</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.src); got != tt.want {
+				t.Errorf("HTML(%q)\n got %s\nwant %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // HTML
+		safe bool   // Validate kabul eder mi
+	}{
+		{"https", "[site](https://a.example.com/x?y=1&z=2)", `<p><a href="https://a.example.com/x?y=1&amp;z=2"` + rel + `>site</a></p>`, true},
+		{"mailto", "[yaz](mailto:ik@example.com)", `<p><a href="mailto:ik@example.com"` + rel + `>yaz</a></p>`, true},
+		{"tel", "[ara](tel:+905551112233)", `<p><a href="tel:+905551112233"` + rel + `>ara</a></p>`, true},
+		{"autolink", "bkz. https://a.example.com.", `<p>bkz. <a href="https://a.example.com"` + rel + `>https://a.example.com</a>.</p>`, true},
+		{"autolink in parentheses", "(https://a.example.com)", `<p>(<a href="https://a.example.com"` + rel + `>https://a.example.com</a>)</p>`, true},
+		{"javascript", "[x](javascript:alert(1))", "<p>x)</p>", false},
+		{"javascript mixed case", "[x](JaVaScRiPt:alert(1))", "<p>x)</p>", false},
+		{"javascript with entity", "[x](javascript&#58;alert(1))", "<p>x)</p>", false},
+		{"data", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>", false},
+		{"vbscript", "[x](vbscript:msgbox)", "<p>x</p>", false},
+		{"file", "[x](file:///etc/passwd)", "<p>x</p>", false},
+		{"scheme-relative", "[x](//evil.example.com)", "<p>x</p>", false},
+		{"relative path", "[x](/api/admin)", "<p>x</p>", false},
+		{"bare host", "[x](evil.example.com)", "<p>x</p>", false},
+		{"http without host", "[x](http:///etc)", "<p>x</p>", false},
+		{"empty mailto", "[x](mailto:)", "<p>x</p>", false},
+		{"control character", "[x](https://a.example.com/\x01)", "<p>x</p>", false},
+		{"NUL before scheme", "[x](\x00javascript:alert(1))", "<p>x)</p>", false},
+		{"DEL in href", "[x](https://a.example.com/\x7f)", "<p>x</p>", false},
+		{"tab splits the link", "[x](java\tscript:alert(1))", "<p>[x](java\tscript:alert(1))</p>", true},
+		{"unsafe link text still rendered", "[**kalın**](javascript:x)", "<p><strong>kalın</strong></p>", false},
+		{"bare javascript is not linked", "javascript:alert(1)", "<p>javascript:alert(1)</p>", true},
+		{"autolink needs a host", "https://", "<p>https://</p>", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.src); got != tt.want {
+				t.Errorf("HTML(%q)\n got %s\nwant %s", tt.src, got, tt.want)
+			}
+			err := Validate(tt.src)
+			if tt.safe && err != nil {
+				t.Errorf("Validate = %v, want nil", err)
+			}
+			if !tt.safe && !errors.Is(err, ErrUnsafeLink) {
+				t.Errorf("Validate = %v, want ErrUnsafeLink", err)
+			}
+		})
+	}
+}
+
+func TestValidateUnsafeHTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		want error
+	}{
+		{"<script>alert(1)</script>", ErrUnsafeHTML},
+		{"< SCRIPT >", ErrUnsafeHTML},
+		{"</script>", ErrUnsafeHTML},
+		{`<iframe src="https://a.example.com">`, ErrUnsafeHTML},
+		{"<svg onload=alert(1)>", ErrUnsafeHTML},
+		{"<style>body{}</style>", ErrUnsafeHTML},
+		{`<form action="https://a.example.com">`, ErrUnsafeHTML},
+		{"`<script>` kod içinde de reddedilir", ErrUnsafeHTML},
+		{"<scripts> bir kelime değil", nil},
+		{"<b>kalın</b> metin olarak gösterilir", nil},
+		{"a < b ve c > d", nil},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.src); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%q) = %v, want %v", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"strong", "**kalın**", "<p><strong>kalın</strong></p>"},
+		{"em star", "*italik*", "<p><em>italik</em></p>"},
+		{"em underscore", "_italik_", "<p><em>italik</em></p>"},
+		{"em inside strong", "**kalın *ve italik***", "<p><strong>kalın <em>ve italik</em></strong></p>"},
+		{"strong inside em", "*italik **ve kalın** metin*", "<p><em>italik <strong>ve kalın</strong> metin</em></p>"},
+		{"strong and em together", "***ikisi***", "<p><strong><em>ikisi</em></strong></p>"},
+		{"em then strong", "*a* ve **b**", "<p><em>a</em> ve <strong>b</strong></p>"},
+		{"underscore em inside strong", "**a _b_ c**", "<p><strong>a <em>b</em> c</strong></p>"},
+		{"link inside strong", "**[a](https://a.example.com)**", `<p><strong><a href="https://a.example.com"` + rel + `>a</a></strong></p>`},
+		{"emphasis inside link", "[*a*](https://a.example.com)", `<p><a href="https://a.example.com"` + rel + `><em>a</em></a></p>`},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>"},
+		{"spaced stars", "2 * 3 * 4", "<p>2 * 3 * 4</p>"},
+		{"unclosed strong", "**açık", "<p>**açık</p>"},
+		{"code span keeps markup", "`**a** [b](c)`", "<p><code>**a** [b](c)</code></p>"},
+		{"code span keeps backslash", "`a\\*b`", `<p><code>a\*b</code></p>`},
+		{"unclosed backtick", "`kod", "<p>`kod</p>"},
+		{"escaped star", `\*değil\*`, "<p>*değil*</p>"},
+		{"emphasis does not span lines", "*a\nb*", "<p>*a<br>\nb*</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.src); got != tt.want {
+				t.Errorf("HTML(%q)\n got %s\nwant %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	src := "# Başlık\n\nparagraf\nikinci satır\n\n- a\n  devam\n* b\n\n3. c\n4. d\n\n> alıntı\n\n---"
+	want := strings.Join([]string{
+		"<h2>Başlık</h2>",
+		"<p>paragraf<br>\nikinci satır</p>",
+		"<ul><li>a devam</li><li>b</li></ul>",
+		`<ol start="3"><li>c</li><li>d</li></ol>`,
+		"<blockquote><p>alıntı</p></blockquote>",
+		"<hr>",
+	}, "\n")
+	if got := HTML(src); got != want {
+		t.Errorf("HTML\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"markup removed", "**kalın** ve *italik* `kod`", "kalın ve italik kod"},
+		{"link shows target", "[site](https://a.example.com)", "site (https://a.example.com)"},
+		{"mailto prefix dropped", "[yaz](mailto:ik@example.com)", "yaz (ik@example.com)"},
+		{"autolink once", "https://a.example.com", "https://a.example.com"},
+		{"unsafe link hides target", "[tıkla](javascript:alert(1))", "tıkla)"},
+		{"raw html kept as text", "<b>x</b>", "<b>x</b>"},
+		{"lists", "- a\n- b\n\n2. c\n3. d", "• a\n• b\n\n2. c\n3. d"},
+		{"blocks and rule", "## Başlık\n\n---\n\n> alıntı\n> satır", "Başlık\n\nalıntı\nsatır"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.src); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// HTTP
+var (
+	HTTPRequests = NewCounterVec("hys_http_requests_total",
+		"HTTP requests by method, route template and status code.", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("hys_http_request_duration_seconds",
+		"HTTP request latency by method and route template.", DefaultBuckets, "method", "route")
+	HTTPResponseSize = NewHistogramVec("hys_http_response_size_bytes",
+		"HTTP response body size by method and route template.", SizeBuckets, "method", "route")
+	HTTPPanics = NewCounterVec("hys_http_panics_total",
+		"Handler panics caught by the recovery middleware.")
+)
+
+// Enibra upstream
+var (
+	EnibraDuration = NewHistogramVec("hys_enibra_request_duration_seconds",
+		"Latency of calls to the Enibra API.", DefaultBuckets, "endpoint")
+	EnibraResponses = NewCounterVec("hys_enibra_responses_total",
+		"Enibra responses by endpoint and upstream status (\"error\" for transport failures).", "endpoint", "status")
+	EnibraCache = NewCounterVec("hys_enibra_cache_lookups_total",
+		"Enibra cache lookups by result (hit|miss).", "result")
+)
+
+// JSON stores under data/
+var (
+	StoreWriteDuration = NewHistogramVec("hys_store_write_duration_seconds",
+		"Duration of JSON store writes.", DefaultBuckets, "store")
+	StoreWriteFailures = NewCounterVec("hys_store_write_failures_total",
+		"Failed JSON store writes.", "store")
+)
+
+// Rate limiting
+var (
+	RateLimitRejections = NewCounterVec("hys_ratelimit_rejections_total",
+		"Requests rejected by rate limits or login lockouts, by scope.", "scope")
+)
+
+// Live stream (/api/stream)
+var (
+	StreamClients = NewGaugeVec("hys_stream_clients",
+		"Connected live stream clients by transport (sse|websocket).", "transport")
+	StreamEvents = NewCounterVec("hys_stream_events_total",
+		"Events published to the live stream by type.", "type")
+	StreamDropped = NewCounterVec("hys_stream_dropped_clients_total",
+		"Live stream clients disconnected for falling behind, by transport.", "transport")
+)
+
+// Background jobs
+var (
+	JobRuns = NewCounterVec("hys_scheduler_runs_total",
+		"Scheduler job runs by outcome (ok|error).", "job", "outcome")
+	JobDuration = NewHistogramVec("hys_scheduler_run_duration_seconds",
+		"Scheduler job run duration.", DefaultBuckets, "job")
+	JobLastRun = NewGaugeVec("hys_scheduler_last_run_timestamp_seconds",
+		"Unix time of the last scheduler run.", "job")
+)
+
+// ObserveEnibra records one upstream call. status is ignored when err is set.
+func ObserveEnibra(endpoint string, start time.Time, status int, err error) {
+	EnibraDuration.ObserveSince(start, endpoint)
+	label := strconv.Itoa(status)
+	if err != nil {
+		label = "error"
+	}
+	EnibraResponses.Inc(endpoint, label)
+}
+
+// ObserveStoreWrite records a write to one of the JSON files.
+func ObserveStoreWrite(store string, start time.Time, err error) {
+	StoreWriteDuration.ObserveSince(start, store)
+	if err != nil {
+		StoreWriteFailures.Inc(store)
+	}
+}
+
+// ObserveJob records one run of a background job.
+func ObserveJob(job string, start time.Time, err error) {
+	JobDuration.ObserveSince(start, job)
+	JobLastRun.Set(float64(time.Now().Unix()), job)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	JobRuns.Inc(job, outcome)
+}
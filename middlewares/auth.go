@@ -2,24 +2,43 @@ package middlewares
 
 import (
 	"net/http"
-	"strings"
-)
 
-// İsteklerde X-Role header'ı bekliyoruz. Örn: "Patron", "IK", "Admin", "Manager", "Personel"
-func RequireRoles(roles ...string) func(http.Handler) http.Handler {
-	allowed := map[string]struct{}{}
-	for _, r := range roles {
-		allowed[strings.ToLower(strings.TrimSpace(r))] = struct{}{}
-	}
+	"hys-go-backend/apierror"
+	"hys-go-backend/rbac"
+)
 
+// İsteklerde X-Role header'ı bekliyoruz: patron, ik, admin, manager, personel
+// (büyük/küçük harf fark etmez). Yetkiler rbac matrisinden okunur.
+//
+// RequirePermission lets the request through when the X-Role role holds perm
+// in the current matrix.
+func RequirePermission(p *rbac.Policy, perm rbac.Permission) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			role := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Role")))
-			if _, ok := allowed[role]; !ok {
-				http.Error(w, "forbidden", http.StatusForbidden)
+			role, ok := rbac.ParseRole(r.Header.Get("X-Role"))
+			if !ok || !p.Allows(role, perm) {
+				apierror.Write(w, r, apierror.Forbidden, map[string]any{"permission": perm})
 				return
 			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// RequireAnyPermission is RequirePermission for handlers that accept several
+// permissions and apply the finer rules themselves.
+func RequireAnyPermission(p *rbac.Policy, perms ...rbac.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if role, ok := rbac.ParseRole(r.Header.Get("X-Role")); ok {
+				for _, perm := range perms {
+					if p.Allows(role, perm) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"permission": perms})
+		})
+	}
+}
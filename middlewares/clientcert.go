@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"hys-go-backend/apierror"
+)
+
+// RequireClientCert rejects requests that did not present a client
+// certificate verified against TLS_CLIENT_CA_FILE.
+func RequireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			apierror.Write(w, r, apierror.Forbidden, map[string]any{"reason": "client_certificate_required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
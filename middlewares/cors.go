@@ -0,0 +1,194 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"hys-go-backend/config"
+
+	"github.com/gorilla/mux"
+)
+
+// CORS answers preflight requests and decorates responses for cross-origin
+// callers. It wraps the whole router (not router.Use) because mux never runs
+// middlewares for OPTIONS requests that match no route method.
+type CORS struct {
+	routes []routeInfo
+	policy atomic.Pointer[corsPolicy]
+}
+
+type routeInfo struct {
+	path    *regexp.Regexp
+	methods []string // nil: her metod
+}
+
+type corsPolicy struct {
+	groups           []originGroup // uzun prefix önce
+	allowCredentials bool
+	allowHeaders     string
+	exposeHeaders    string
+	maxAge           string
+}
+
+// originGroup applies its own origin allowlist to every path under prefix.
+type originGroup struct {
+	prefix   string
+	allowAll bool
+	origins  map[string]struct{}
+}
+
+// NewCORS indexes the routes already registered on router and applies cfg.
+// adminPrefix gets cfg.AdminOrigins when that list is set.
+func NewCORS(router *mux.Router, cfg config.CORSConfig, adminPrefix string) *CORS {
+	c := &CORS{}
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil // alt router kabı, kendisi bir uç değil
+		}
+		re, err := route.GetPathRegexp()
+		if err != nil {
+			return nil
+		}
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		c.routes = append(c.routes, routeInfo{path: compiled, methods: methods})
+		return nil
+	})
+	c.Update(cfg, adminPrefix)
+	return c
+}
+
+// Update swaps the policy, e.g. after a configuration reload.
+func (c *CORS) Update(cfg config.CORSConfig, adminPrefix string) {
+	groups := map[string][]string{"/": cfg.AllowedOrigins}
+	if len(cfg.AdminOrigins) > 0 && adminPrefix != "" {
+		groups[adminPrefix] = cfg.AdminOrigins
+	}
+
+	p := &corsPolicy{
+		allowCredentials: cfg.AllowCredentials,
+		allowHeaders:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cfg.ExposedHeaders, ", "),
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for prefix, origins := range groups {
+		og := originGroup{prefix: prefix, origins: map[string]struct{}{}}
+		for _, o := range origins {
+			if o == "*" {
+				og.allowAll = true
+				continue
+			}
+			og.origins[strings.ToLower(strings.TrimRight(o, "/"))] = struct{}{}
+		}
+		p.groups = append(p.groups, og)
+	}
+	sort.Slice(p.groups, func(i, j int) bool { return len(p.groups[i].prefix) > len(p.groups[j].prefix) })
+	c.policy.Store(p)
+}
+
+// Handler wraps next (normally the router).
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.policy.Load()
+		origin := r.Header.Get("Origin")
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if origin == "" {
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowOrigin := p.allowOrigin(r.URL.Path, origin)
+		if allowOrigin != "" {
+			h.Set("Access-Control-Allow-Origin", allowOrigin)
+			if p.allowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			if allowOrigin != "" && p.exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		methods := c.methodsFor(r.URL.Path)
+		if len(methods) == 0 {
+			next.ServeHTTP(w, r) // bilinmeyen yol: router 404 döner
+			return
+		}
+		if allowOrigin != "" {
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowOrigin returns the value for Access-Control-Allow-Origin, or "" when
+// origin is not allowed for path.
+func (p *corsPolicy) allowOrigin(path, origin string) string {
+	for _, g := range p.groups {
+		if !strings.HasPrefix(path, g.prefix) {
+			continue
+		}
+		if _, ok := g.origins[strings.ToLower(origin)]; ok {
+			return origin
+		}
+		if g.allowAll {
+			return "*"
+		}
+		return ""
+	}
+	return ""
+}
+
+var allMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete,
+}
+
+// methodsFor returns every method registered for path, plus OPTIONS.
+func (c *CORS) methodsFor(path string) []string {
+	set := map[string]struct{}{}
+	for _, rt := range c.routes {
+		if !rt.path.MatchString(path) {
+			continue
+		}
+		ms := rt.methods
+		if len(ms) == 0 {
+			ms = allMethods
+		}
+		for _, m := range ms {
+			set[m] = struct{}{}
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	set[http.MethodOptions] = struct{}{}
+	out := make([]string, 0, len(set))
+	for m := range set {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
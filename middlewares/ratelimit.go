@@ -0,0 +1,215 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/config"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
+	"hys-go-backend/ratelimit"
+)
+
+// ipLockoutFactor: bir mağazadaki herkes aynı NAT IP'sinden gelir, bu yüzden
+// IP kilidi TC kilidinden daha geç devreye girer.
+const ipLockoutFactor = 4
+
+// RateLimits holds every limiter and lockout tracker used by the router.
+type RateLimits struct {
+	cfg config.RateLimitConfig
+
+	public  *ratelimit.Limiter
+	enibra  *ratelimit.Limiter
+	loginIP *ratelimit.Limiter
+	loginTC *ratelimit.Limiter
+	tcLocks *ratelimit.Lockouts
+	ipLocks *ratelimit.Lockouts
+}
+
+// NewRateLimits builds the limiters from cfg. Lockout state is kept in
+// dataDir when cfg.PersistLockouts is set.
+func NewRateLimits(cfg config.RateLimitConfig, dataDir string) *RateLimits {
+	policy := ratelimit.LockoutPolicy{Threshold: cfg.LockoutThreshold, Base: cfg.LockoutBase, Max: cfg.LockoutMax}
+	ipPolicy := policy
+	ipPolicy.Threshold *= ipLockoutFactor
+
+	var tcStore, ipStore ratelimit.Store
+	if cfg.PersistLockouts {
+		tcStore = ratelimit.FileStore{Path: filepath.Join(dataDir, "lockouts_tc.json")}
+		ipStore = ratelimit.FileStore{Path: filepath.Join(dataDir, "lockouts_ip.json")}
+	}
+
+	return &RateLimits{
+		cfg:     cfg,
+		public:  newLimiter(cfg.PublicPerIP),
+		enibra:  newLimiter(cfg.EnibraPerIP),
+		loginIP: newLimiter(cfg.LoginPerIP),
+		loginTC: newLimiter(cfg.LoginPerTC),
+		tcLocks: ratelimit.NewLockouts(policy, tcStore),
+		ipLocks: ratelimit.NewLockouts(ipPolicy, ipStore),
+	}
+}
+
+func newLimiter(perMinute int) *ratelimit.Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return ratelimit.NewLimiter(perMinute, perMinute)
+}
+
+// Lockouts returns the TC and IP lockout trackers for the admin endpoints.
+func (rl *RateLimits) Lockouts() (tc, ip *ratelimit.Lockouts) {
+	return rl.tcLocks, rl.ipLocks
+}
+
+// Public limits every API request per client IP.
+func (rl *RateLimits) Public(next http.Handler) http.Handler {
+	return rl.perIP("public", rl.public, next)
+}
+
+// Enibra limits /api/enibra/* per client IP to protect the upstream.
+func (rl *RateLimits) Enibra(next http.Handler) http.Handler {
+	return rl.perIP("enibra", rl.enibra, next)
+}
+
+func (rl *RateLimits) perIP(scope string, lim *ratelimit.Limiter, next http.Handler) http.Handler {
+	if !rl.cfg.Enabled || lim == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := lim.Allow(ClientIP(r, rl.cfg.TrustProxyHeaders)); !ok {
+			rejectRate(w, r, scope, apierror.RateLimited, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Login guards the login endpoint: token buckets per IP and per TC, plus a
+// progressive lockout driven by the handler's response status.
+func (rl *RateLimits) Login(next http.Handler) http.Handler {
+	if !rl.cfg.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r, rl.cfg.TrustProxyHeaders)
+		tc := peekTC(r)
+
+		if locked, left := rl.ipLocks.Locked(ip); locked {
+			rejectRate(w, r, "login_lockout_ip", apierror.LockedOut, left)
+			return
+		}
+		if tc != "" {
+			if locked, left := rl.tcLocks.Locked(tc); locked {
+				rejectRate(w, r, "login_lockout_tc", apierror.LockedOut, left)
+				return
+			}
+		}
+		if rl.loginIP != nil {
+			if ok, wait := rl.loginIP.Allow(ip); !ok {
+				rejectRate(w, r, "login_ip", apierror.RateLimited, wait)
+				return
+			}
+		}
+		if rl.loginTC != nil && tc != "" {
+			if ok, wait := rl.loginTC.Allow(tc); !ok {
+				rejectRate(w, r, "login_tc", apierror.RateLimited, wait)
+				return
+			}
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		switch {
+		case sw.status >= 200 && sw.status < 300:
+			rl.ipLocks.Succeed(ip)
+			if tc != "" {
+				rl.tcLocks.Succeed(tc)
+			}
+		case isLoginFailure(sw.status):
+			rl.ipLocks.Fail(ip)
+			if tc != "" {
+				rl.tcLocks.Fail(tc)
+			}
+		}
+	})
+}
+
+// isLoginFailure counts only real authentication failures. 400/422 (bozuk
+// gövde, hatalı kontrol hanesi) sayılmaz; yoksa herkes başkasının TC'siyle
+// bozuk istek atarak o kişiyi kilitleyebilirdi. GirisHandler kayıtlı
+// olmayan TC için 401 döner.
+func isLoginFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+func rejectRate(w http.ResponseWriter, r *http.Request, scope string, code apierror.Code, wait time.Duration) {
+	metrics.RateLimitRejections.Inc(scope)
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	apierror.Write(w, r, code, map[string]any{"retry_after_sec": secs})
+}
+
+// peekTC reads the login body to find the TC and puts the body back for the
+// handler.
+func peekTC(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(b), r.Body))
+	if err != nil {
+		return ""
+	}
+	var in struct {
+		TC string `json:"tc_kimlik_no"`
+	}
+	_ = json.Unmarshal(b, &in)
+	return identity.Normalize(in.TC)
+}
+
+// ClientIP returns the caller's IP. Forwarding headers are only honored when
+// trustProxy is set, i.e. when a reverse proxy we control sets them.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter { return sw.ResponseWriter }
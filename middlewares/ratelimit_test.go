@@ -0,0 +1,164 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hys-go-backend/config"
+)
+
+const (
+	tcA = "25031519376"
+	tcB = "10000000146"
+)
+
+// loginStub answers every login with the status currently in *status.
+func loginStub(status *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(*status)
+	})
+}
+
+func newLoginLimits(base time.Duration) *RateLimits {
+	return NewRateLimits(config.RateLimitConfig{
+		Enabled:          true,
+		LockoutThreshold: 2,
+		LockoutBase:      base,
+		LockoutMax:       time.Hour,
+	}, "")
+}
+
+func login(h http.Handler, tc string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/giris", strings.NewReader(`{"tc_kimlik_no":"`+tc+`"}`))
+	req.RemoteAddr = "192.0.2.10:5000"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginLocksAfterThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantLocked bool
+	}{
+		{"unauthorized counts", http.StatusUnauthorized, true},
+		{"forbidden counts", http.StatusForbidden, true},
+		{"bad request does not count", http.StatusBadRequest, false},
+		{"validation error does not count", http.StatusUnprocessableEntity, false},
+		{"upstream error does not count", http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := newLoginLimits(time.Minute)
+			status := tt.status
+			h := rl.Login(loginStub(&status))
+
+			for i := 0; i < 2; i++ {
+				if rec := login(h, tcA); rec.Code != tt.status {
+					t.Fatalf("attempt %d: status %d, want %d", i+1, rec.Code, tt.status)
+				}
+			}
+			rec := login(h, tcA)
+			if locked := rec.Code == http.StatusTooManyRequests; locked != tt.wantLocked {
+				t.Fatalf("third attempt: status %d, locked = %v, want %v", rec.Code, locked, tt.wantLocked)
+			}
+			if !tt.wantLocked {
+				return
+			}
+			if got := rec.Header().Get("Retry-After"); got != "60" {
+				t.Errorf("Retry-After = %q, want 60", got)
+			}
+			if !strings.Contains(rec.Body.String(), "too_many_failed_attempts") {
+				t.Errorf("body = %s", rec.Body)
+			}
+			if rec := login(h, tcB); rec.Code != tt.status {
+				t.Errorf("other TC: status %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestLoginLockoutEscalates(t *testing.T) {
+	const base = 40 * time.Millisecond
+	rl := newLoginLimits(base)
+	tcLocks, _ := rl.Lockouts()
+	status := http.StatusUnauthorized
+	h := rl.Login(loginStub(&status))
+
+	var last time.Duration
+	for round := 1; round <= 3; round++ {
+		login(h, tcA)
+		login(h, tcA)
+		locked, left := tcLocks.Locked(tcA)
+		if !locked {
+			t.Fatalf("round %d: not locked", round)
+		}
+		want := base << (round - 1)
+		if left <= last || left > want {
+			t.Fatalf("round %d: lock %v, want (%v, %v]", round, left, last, want)
+		}
+		if rec := login(h, tcA); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("round %d: status %d while locked", round, rec.Code)
+		}
+		last = want
+		time.Sleep(left + 5*time.Millisecond)
+	}
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	rl := newLoginLimits(time.Minute)
+	status := http.StatusUnauthorized
+	h := rl.Login(loginStub(&status))
+
+	login(h, tcA)
+	status = http.StatusOK
+	login(h, tcA)
+	status = http.StatusUnauthorized
+	login(h, tcA)
+	if rec := login(h, tcA); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d: failures before the success were still counted", rec.Code)
+	}
+	if rec := login(h, tcA); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want lock after two fresh failures", rec.Code)
+	}
+}
+
+func TestLoginClearLockoutUnlocks(t *testing.T) {
+	rl := newLoginLimits(time.Minute)
+	tcLocks, _ := rl.Lockouts()
+	status := http.StatusUnauthorized
+	h := rl.Login(loginStub(&status))
+
+	login(h, tcA)
+	login(h, tcA)
+	if rec := login(h, tcA); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want lock", rec.Code)
+	}
+	if !tcLocks.Clear(tcA) {
+		t.Fatal("Clear found no lock")
+	}
+	if rec := login(h, tcA); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d after Clear, want the handler's 401", rec.Code)
+	}
+}
+
+func TestLoginLocksIPAfterFactor(t *testing.T) {
+	rl := newLoginLimits(time.Minute)
+	status := http.StatusUnauthorized
+	h := rl.Login(loginStub(&status))
+
+	// Her TC bir kez dener; IP kilidi 2*ipLockoutFactor hatada gelir.
+	for i := 0; i < 2*ipLockoutFactor; i++ {
+		if rec := login(h, fmt.Sprintf("100000000%02d", i)); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d", i+1, rec.Code)
+		}
+	}
+	if rec := login(h, tcA); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want IP lock", rec.Code)
+	}
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
+	"hys-go-backend/reqid"
+)
+
+// Recover turns a handler panic into a logged stack trace and a 500 error
+// envelope instead of a dropped connection.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec) // net/http bunu bilerek kullanır, sessizce bağlantıyı keser
+			}
+			metrics.HTTPPanics.Inc()
+			log.Printf("[ERROR] panic serving %s %s rid=%s: %v\n%s", r.Method, identity.Redact(r.URL.Path), reqid.From(r.Context()), rec, debug.Stack())
+			apierror.Write(w, r, apierror.Internal, nil)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
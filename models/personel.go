@@ -1,9 +1,26 @@
 package models
 
+import (
+	"strings"
+
+	"hys-go-backend/apierror"
+	"hys-go-backend/identity"
+)
+
 type GirisRequest struct {
 	TCKimlikNo string `json:"tc_kimlik_no"`
 }
 
+func (g *GirisRequest) Validate() []apierror.FieldError {
+	if strings.TrimSpace(g.TCKimlikNo) == "" {
+		return []apierror.FieldError{{Field: "tc_kimlik_no", Code: "required"}}
+	}
+	if err := identity.Validate(identity.Normalize(g.TCKimlikNo)); err != nil {
+		return []apierror.FieldError{{Field: "tc_kimlik_no", Code: "invalid", Message: err.Error()}}
+	}
+	return nil
+}
+
 type PersonelDetayRequest struct {
 	InsanID int `json:"insan_id"`
 }
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is advanced by hand; tests never sleep.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(perMinute, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	l := NewLimiter(perMinute, burst)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		allowed   int
+	}{
+		{"burst equals rate", 60, 60, 60},
+		{"small burst", 60, 3, 3},
+		{"burst below one is one", 60, 0, 1},
+		{"zero rate still bursts", 0, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newTestLimiter(tt.perMinute, tt.burst)
+			for i := 0; i < tt.allowed; i++ {
+				if ok, _ := l.Allow("k"); !ok {
+					t.Fatalf("request %d rejected inside the burst", i+1)
+				}
+			}
+			if ok, _ := l.Allow("k"); ok {
+				t.Fatalf("request %d allowed past the burst", tt.allowed+1)
+			}
+		})
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int // kaç istek daha geçer
+	}{
+		{"nothing elapsed", 0, 0},
+		{"less than one token", 500 * time.Millisecond, 0},
+		{"one token", time.Second, 1},
+		{"two and a half tokens", 2500 * time.Millisecond, 2},
+		{"capped at burst", time.Hour, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(60, 3) // saniyede bir jeton
+			for i := 0; i < 3; i++ {
+				l.Allow("k")
+			}
+			clock.advance(tt.elapsed)
+			got := 0
+			for {
+				if ok, _ := l.Allow("k"); !ok {
+					break
+				}
+				got++
+			}
+			if got != tt.want {
+				t.Errorf("allowed %d after %v, want %d", got, tt.elapsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterRetryAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		elapsed   time.Duration
+		want      time.Duration
+	}{
+		{"one per second, empty", 60, 0, time.Second},
+		{"one per second, partly refilled", 60, 400 * time.Millisecond, 600 * time.Millisecond},
+		{"six per minute", 6, 0, 10 * time.Second},
+		{"six per minute, partly refilled", 6, 4 * time.Second, 6 * time.Second},
+		{"zero rate", 0, 0, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.perMinute, 1)
+			if ok, _ := l.Allow("k"); !ok {
+				t.Fatal("first request rejected")
+			}
+			clock.advance(tt.elapsed)
+			ok, wait := l.Allow("k")
+			if ok {
+				t.Fatal("second request allowed")
+			}
+			if d := wait - tt.want; d < -time.Millisecond || d > time.Millisecond {
+				t.Errorf("wait = %v, want %v", wait, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(60, 1)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("a rejected")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("a allowed twice")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("b rejected because of a")
+	}
+}
+
+func TestLimiterSweepDropsFullBuckets(t *testing.T) {
+	l, clock := newTestLimiter(60, 2)
+	l.Allow("idle")
+	clock.advance(time.Minute)
+	for i := 0; i < 1023; i++ {
+		l.Allow("busy")
+		clock.advance(time.Second)
+	}
+	l.mu.Lock()
+	_, ok := l.buckets["idle"]
+	l.mu.Unlock()
+	if ok {
+		t.Error("idle bucket survived the sweep")
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"hys-go-backend/metrics"
+)
+
+// FileStore keeps lockout state in a JSON file (tmp + rename, like the
+// other stores under data/).
+type FileStore struct {
+	Path string
+}
+
+func (f FileStore) Load() ([]LockoutState, error) {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []LockoutState
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (f FileStore) Save(list []LockoutState) (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("lockouts", start, err) }(time.Now())
+
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
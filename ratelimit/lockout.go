@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LockoutPolicy: Threshold failures lock the key for Base; every further
+// lock doubles the duration up to Max.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// LockoutState is what is tracked (and optionally persisted) per key.
+type LockoutState struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	Level       int       `json:"level"` // kaç kez kilitlendi
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// Store persists lockout state between restarts.
+type Store interface {
+	Load() ([]LockoutState, error)
+	Save([]LockoutState) error
+}
+
+// Lockouts tracks failed attempts per key.
+type Lockouts struct {
+	policy LockoutPolicy
+	store  Store
+
+	mu    sync.Mutex
+	state map[string]*LockoutState
+	now   func() time.Time
+}
+
+// NewLockouts loads any persisted state from store (which may be nil).
+func NewLockouts(policy LockoutPolicy, store Store) *Lockouts {
+	l := &Lockouts{policy: policy, store: store, state: map[string]*LockoutState{}, now: time.Now}
+	if store != nil {
+		list, err := store.Load()
+		if err != nil {
+			log.Printf("[WARN] lockout state could not be loaded: %v", err)
+		}
+		for i := range list {
+			st := list[i]
+			l.state[st.Key] = &st
+		}
+	}
+	return l
+}
+
+// Locked reports whether key is locked and for how much longer.
+func (l *Lockouts) Locked(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.state[key]
+	if !ok {
+		return false, 0
+	}
+	if left := st.LockedUntil.Sub(l.now()); left > 0 {
+		return true, left
+	}
+	return false, 0
+}
+
+// Fail records a failed attempt and returns the lock duration if this
+// failure triggered a lock.
+func (l *Lockouts) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	st, ok := l.state[key]
+	if !ok {
+		st = &LockoutState{Key: key}
+		l.state[key] = st
+	}
+	// Uzun süre sessiz kalan anahtar sıfırdan başlar.
+	if !st.LastFailure.IsZero() && now.Sub(st.LastFailure) > l.policy.Max {
+		st.Failures, st.Level = 0, 0
+	}
+	st.Failures++
+	st.LastFailure = now
+
+	var lock time.Duration
+	if l.policy.Threshold > 0 && st.Failures >= l.policy.Threshold {
+		lock = l.policy.Base << st.Level
+		if lock > l.policy.Max || lock <= 0 {
+			lock = l.policy.Max
+		}
+		st.LockedUntil = now.Add(lock)
+		st.Failures = 0
+		st.Level++
+	}
+	l.persistLocked()
+	return lock
+}
+
+// Succeed clears the failure history of key.
+func (l *Lockouts) Succeed(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.state[key]; ok {
+		delete(l.state, key)
+		l.persistLocked()
+	}
+}
+
+// List returns keys with failures or an active lock, most recent first.
+func (l *Lockouts) List() []LockoutState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]LockoutState, 0, len(l.state))
+	for _, st := range l.state {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastFailure.After(out[j].LastFailure) })
+	return out
+}
+
+// Clear removes key; it returns false when key was not tracked.
+func (l *Lockouts) Clear(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.state[key]; !ok {
+		return false
+	}
+	delete(l.state, key)
+	l.persistLocked()
+	return true
+}
+
+func (l *Lockouts) persistLocked() {
+	if l.store == nil {
+		return
+	}
+	list := make([]LockoutState, 0, len(l.state))
+	for _, st := range l.state {
+		list = append(list, *st)
+	}
+	if err := l.store.Save(list); err != nil {
+		log.Printf("[WARN] lockout state could not be saved: %v", err)
+	}
+}
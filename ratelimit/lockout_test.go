@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testPolicy = LockoutPolicy{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+
+func newTestLockouts(store Store) (*Lockouts, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	l := NewLockouts(testPolicy, store)
+	l.now = clock.now
+	return l, clock
+}
+
+// failUntilLocked records failures until one of them locks key and returns
+// that lock duration.
+func failUntilLocked(t *testing.T, l *Lockouts, key string) time.Duration {
+	t.Helper()
+	for i := 0; i < testPolicy.Threshold; i++ {
+		if lock := l.Fail(key); lock > 0 {
+			if i != testPolicy.Threshold-1 {
+				t.Fatalf("locked after %d failures, want %d", i+1, testPolicy.Threshold)
+			}
+			return lock
+		}
+	}
+	t.Fatalf("not locked after %d failures", testPolicy.Threshold)
+	return 0
+}
+
+func TestLockoutEscalation(t *testing.T) {
+	l, clock := newTestLockouts(nil)
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, w := range want {
+		lock := failUntilLocked(t, l, "k")
+		if lock != w {
+			t.Fatalf("lock %d = %v, want %v", i+1, lock, w)
+		}
+		if locked, left := l.Locked("k"); !locked || left != w {
+			t.Fatalf("Locked after lock %d = %v, %v", i+1, locked, left)
+		}
+		clock.advance(lock)
+		if locked, _ := l.Locked("k"); locked {
+			t.Fatalf("still locked after lock %d expired", i+1)
+		}
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset func(l *Lockouts, clock *fakeClock)
+		want  time.Duration // bir sonraki kilidin süresi
+	}{
+		{"no reset escalates", func(*Lockouts, *fakeClock) {}, 2 * time.Minute},
+		{"success resets", func(l *Lockouts, _ *fakeClock) { l.Succeed("k") }, time.Minute},
+		{"clear resets", func(l *Lockouts, _ *fakeClock) { l.Clear("k") }, time.Minute},
+		{"quiet longer than max resets", func(_ *Lockouts, c *fakeClock) { c.advance(testPolicy.Max + time.Second) }, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLockouts(nil)
+			clock.advance(failUntilLocked(t, l, "k"))
+			tt.reset(l, clock)
+			if got := failUntilLocked(t, l, "k"); got != tt.want {
+				t.Errorf("next lock = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutSuccessClearsFailures(t *testing.T) {
+	l, _ := newTestLockouts(nil)
+	l.Fail("k")
+	l.Fail("k")
+	l.Succeed("k")
+	if lock := l.Fail("k"); lock != 0 {
+		t.Fatalf("locked after one failure following a success: %v", lock)
+	}
+}
+
+func TestLockoutClear(t *testing.T) {
+	l, _ := newTestLockouts(nil)
+	failUntilLocked(t, l, "k")
+	if !l.Clear("k") {
+		t.Fatal("Clear(k) = false for a locked key")
+	}
+	if locked, _ := l.Locked("k"); locked {
+		t.Fatal("still locked after Clear")
+	}
+	if l.Clear("k") {
+		t.Fatal("Clear(k) = true for an untracked key")
+	}
+	if len(l.List()) != 0 {
+		t.Fatalf("List after Clear = %v", l.List())
+	}
+}
+
+func TestLockoutFileStoreRoundTrip(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "lockouts.json")}
+	l, clock := newTestLockouts(store)
+	failUntilLocked(t, l, "k")
+
+	reloaded := NewLockouts(testPolicy, store)
+	reloaded.now = clock.now
+	if locked, left := reloaded.Locked("k"); !locked || left != time.Minute {
+		t.Fatalf("reloaded Locked = %v, %v; want true, 1m", locked, left)
+	}
+	if got := failUntilLocked(t, reloaded, "k"); got != 2*time.Minute {
+		t.Errorf("escalation after reload = %v, want 2m", got)
+	}
+}
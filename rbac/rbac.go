@@ -0,0 +1,257 @@
+// Package rbac defines the fixed set of roles, the named permissions and an
+// editable role→permission matrix persisted under the data directory.
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hys-go-backend/metrics"
+)
+
+type Role string
+
+const (
+	Patron   Role = "patron"
+	IK       Role = "ik"
+	Admin    Role = "admin"
+	Manager  Role = "manager"
+	Personel Role = "personel"
+)
+
+// Roles lists every assignable role.
+var Roles = []Role{Patron, IK, Admin, Manager, Personel}
+
+// ParseRole matches s case-insensitively ("Patron", "IK", "admin", ...).
+func ParseRole(s string) (Role, bool) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Roles {
+		if r == known {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+type Permission string
+
+const (
+	AnnouncementCreate   Permission = "announcement.create"
+	AnnouncementManage   Permission = "announcement.manage"
+	AllowlistManage      Permission = "allowlist.manage"
+	AttendanceViewBranch Permission = "attendance.view_branch"
+	AttendanceViewRegion Permission = "attendance.view_region"
+	AttendanceViewAll    Permission = "attendance.view_all"
+	PersonnelViewBranch  Permission = "personnel.view_branch"
+	PersonnelViewRegion  Permission = "personnel.view_region"
+	PersonnelViewAll     Permission = "personnel.view_all"
+	AuditView            Permission = "audit.view"
+	LockoutsManage       Permission = "lockouts.manage"
+	RolesManage          Permission = "roles.manage"
+)
+
+// Onay akışı (bkz. handlers/announcement_workflow.go): create_branch yalnızca
+// kendi şube(ler)ine doğrudan yayın yapar, şirket geneline gönderilenler
+// announcement.approve yetkisi olanların onayına düşer. moderate, yorumları
+// gizleme ve silme yetkisidir (bkz. handlers/announcement_comments.go).
+const (
+	AnnouncementCreateBranch Permission = "announcement.create_branch"
+	AnnouncementApprove      Permission = "announcement.approve"
+	AnnouncementModerate     Permission = "announcement.moderate"
+)
+
+// Permissions lists every known permission.
+var Permissions = []Permission{
+	AnnouncementCreate, AnnouncementManage, AnnouncementCreateBranch, AnnouncementApprove,
+	AnnouncementModerate,
+	AllowlistManage,
+	AttendanceViewBranch, AttendanceViewRegion, AttendanceViewAll,
+	PersonnelViewBranch, PersonnelViewRegion, PersonnelViewAll,
+	AuditView, LockoutsManage, RolesManage,
+}
+
+func knownPermission(p Permission) bool {
+	for _, known := range Permissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultMatrix mirrors the access rules that were hard-coded in the router
+// before the matrix existed. Patron her zaman her yetkiye sahiptir.
+func DefaultMatrix() map[Role][]Permission {
+	return map[Role][]Permission{
+		Patron: Permissions,
+		IK: {
+			AnnouncementCreate, AnnouncementManage, AnnouncementModerate, AttendanceViewAll, PersonnelViewAll,
+		},
+		Admin: {
+			AllowlistManage, AuditView, LockoutsManage, RolesManage,
+			AttendanceViewAll, PersonnelViewAll,
+		},
+		Manager: {
+			AnnouncementCreateBranch, AttendanceViewBranch, PersonnelViewBranch,
+		},
+		Personel: {},
+	}
+}
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrImmutableRole     = errors.New("role cannot be edited")
+)
+
+// Policy is the live matrix. It is safe for concurrent use.
+type Policy struct {
+	path string
+
+	mu     sync.RWMutex
+	grants map[Role]map[Permission]struct{}
+}
+
+// NewPolicy loads path, or starts from DefaultMatrix when the file does not
+// exist yet. Roles missing from the file keep their defaults.
+func NewPolicy(path string) (*Policy, error) {
+	p := &Policy{path: path}
+	p.grants = toGrants(DefaultMatrix())
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return p, err
+	}
+	var stored map[string][]string
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return p, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, perms := range stored {
+		role, ok := ParseRole(name)
+		if !ok {
+			return p, fmt.Errorf("%s: %w %q", path, ErrUnknownRole, name)
+		}
+		if role == Patron {
+			continue
+		}
+		set, err := parsePermissions(perms)
+		if err != nil {
+			return p, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		p.grants[role] = set
+	}
+	return p, nil
+}
+
+// Allows reports whether role has perm. Unknown roles have nothing.
+func (p *Policy) Allows(role Role, perm Permission) bool {
+	if role == Patron {
+		return true
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.grants[role][perm]
+	return ok
+}
+
+// Matrix returns a sorted copy of the current mapping.
+func (p *Policy) Matrix() map[Role][]Permission {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make(map[Role][]Permission, len(p.grants))
+	for role, set := range p.grants {
+		out[role] = sortedPermissions(set)
+	}
+	return out
+}
+
+// Set replaces the permissions of role and persists the matrix. It returns
+// the previous permissions for auditing.
+func (p *Policy) Set(role Role, perms []string) (before []Permission, err error) {
+	if role == Patron {
+		return nil, ErrImmutableRole
+	}
+	if _, ok := ParseRole(string(role)); !ok {
+		return nil, ErrUnknownRole
+	}
+	set, err := parsePermissions(perms)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prev := p.grants[role]
+	p.grants[role] = set
+	if err := p.persistLocked(); err != nil {
+		p.grants[role] = prev
+		return nil, err
+	}
+	return sortedPermissions(prev), nil
+}
+
+func (p *Policy) persistLocked() (err error) {
+	defer func(start time.Time) { metrics.ObserveStoreWrite("roles", start, err) }(time.Now())
+
+	out := make(map[Role][]Permission, len(p.grants))
+	for role, set := range p.grants {
+		if role != Patron {
+			out[role] = sortedPermissions(set)
+		}
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+func parsePermissions(perms []string) (map[Permission]struct{}, error) {
+	set := make(map[Permission]struct{}, len(perms))
+	for _, s := range perms {
+		perm := Permission(strings.TrimSpace(s))
+		if !knownPermission(perm) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownPermission, s)
+		}
+		set[perm] = struct{}{}
+	}
+	return set, nil
+}
+
+func toGrants(m map[Role][]Permission) map[Role]map[Permission]struct{} {
+	out := make(map[Role]map[Permission]struct{}, len(m))
+	for role, perms := range m {
+		set := make(map[Permission]struct{}, len(perms))
+		for _, perm := range perms {
+			set[perm] = struct{}{}
+		}
+		out[role] = set
+	}
+	return out
+}
+
+func sortedPermissions(set map[Permission]struct{}) []Permission {
+	out := make([]Permission, 0, len(set))
+	for perm := range set {
+		out = append(out, perm)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
@@ -0,0 +1,48 @@
+// Package reqid assigns and propagates a per-request correlation ID.
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// Header is read from incoming requests and echoed on responses.
+const Header = "X-Request-ID"
+
+type ctxKey struct{}
+
+// Gelen başlık yalnızca makul karakterlerden oluşuyorsa kullanılır.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// New returns a random 16-byte hex ID.
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// With stores id in ctx.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// From returns the request ID stored in ctx, or "".
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Middleware reuses a well-formed X-Request-ID or generates a new one.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validID.MatchString(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), id)))
+	})
+}
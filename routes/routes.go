@@ -3,46 +3,129 @@ package routes
 import (
 	"log"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
+	"hys-go-backend/apierror"
+	"hys-go-backend/config"
 	"hys-go-backend/handlers"
+	"hys-go-backend/identity"
+	"hys-go-backend/metrics"
+	"hys-go-backend/middlewares"
+	"hys-go-backend/rbac"
+	"hys-go-backend/reqid"
 
 	"github.com/gorilla/mux"
 )
 
-// NewRouter wires all application routes and middlewares.
-func NewRouter() *mux.Router {
+const adminPrefix = "/api/admin"
+
+var cors *middlewares.CORS
+
+// NewRouter wires all application routes and middlewares to the handlers
+// in h, which main built from the same cfg.
+func NewRouter(cfg *config.Config, h *handlers.API) http.Handler {
 	r := mux.NewRouter()
-	r.Use(corsMiddleware)
+	r.Use(reqid.Middleware)
 	r.Use(loggingMiddleware)
+	r.Use(middlewares.Recover)
+	r.Use(h.Authenticate)
+	r.Use(h.AllowlistRoles)
+
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", h.Health).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", h.Ready).Methods(http.MethodGet)
+
+	policy := h.Policy()
+	can := func(perm rbac.Permission, h http.HandlerFunc) http.Handler {
+		return middlewares.RequirePermission(policy, perm)(h)
+	}
+	canAny := func(h http.HandlerFunc, perms ...rbac.Permission) http.Handler {
+		return middlewares.RequireAnyPermission(policy, perms...)(h)
+	}
+
+	limits := middlewares.NewRateLimits(cfg.Limits, cfg.DataDir)
+	tcLocks, ipLocks := limits.Lockouts()
 
 	api := r.PathPrefix("/api").Subrouter()
-	api.HandleFunc("/personel", handlers.PersonelList).Methods(http.MethodGet)
+	api.Use(limits.Public)
+	api.HandleFunc("/personel", h.PersonelList).Methods(http.MethodGet)
+	api.Handle("/giris", limits.Login(http.HandlerFunc(h.GirisHandler))).Methods(http.MethodPost)
 
-	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handlers.WriteJSON(w, http.StatusNotFound, map[string]any{
-			"error": "not_found",
-			"path":  r.URL.Path,
-		})
-	})
+	api.HandleFunc("/stream", h.Stream).Methods(http.MethodGet)
+	api.HandleFunc("/stream/token", h.IssueStreamToken).Methods(http.MethodPost)
+	api.HandleFunc("/announcements", h.ListAnnouncements).Methods(http.MethodGet)
+	api.Handle("/announcements", canAny(h.CreateAnnouncement, rbac.AnnouncementCreate, rbac.AnnouncementCreateBranch)).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/drafts", h.ListAnnouncementDrafts).Methods(http.MethodGet)
+	api.HandleFunc("/announcements/pending-ack", h.ListPendingAcks).Methods(http.MethodGet)
+	api.Handle("/announcements/review-queue", can(rbac.AnnouncementApprove, h.ListReviewQueue)).Methods(http.MethodGet)
+	api.HandleFunc("/announcements/{id}", h.GetAnnouncement).Methods(http.MethodGet)
+	api.Handle("/announcements/{id}", canAny(h.UpdateAnnouncement, rbac.AnnouncementManage, rbac.AnnouncementCreateBranch)).Methods(http.MethodPut, http.MethodPatch)
+	api.Handle("/announcements/{id}", can(rbac.AnnouncementManage, h.DeleteAnnouncement)).Methods(http.MethodDelete)
+	api.Handle("/announcements/{id}/submit", canAny(h.SubmitAnnouncement, rbac.AnnouncementManage, rbac.AnnouncementCreate, rbac.AnnouncementCreateBranch)).Methods(http.MethodPost)
+	api.Handle("/announcements/{id}/withdraw", canAny(h.WithdrawAnnouncement, rbac.AnnouncementManage, rbac.AnnouncementCreate, rbac.AnnouncementCreateBranch)).Methods(http.MethodPost)
+	api.Handle("/announcements/{id}/approve", can(rbac.AnnouncementApprove, h.ApproveAnnouncement)).Methods(http.MethodPost)
+	api.Handle("/announcements/{id}/reject", can(rbac.AnnouncementApprove, h.RejectAnnouncement)).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/{id}/read", h.MarkAnnouncementRead).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/{id}/ack", h.AckAnnouncement).Methods(http.MethodPost)
+	api.Handle("/announcements/{id}/receipts", can(rbac.AnnouncementManage, h.AnnouncementReceipts)).Methods(http.MethodGet)
+	api.HandleFunc("/announcements/{id}/comments", h.ListComments).Methods(http.MethodGet)
+	api.HandleFunc("/announcements/{id}/comments", h.CreateComment).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/{id}/comments/{cid}", h.DeleteComment).Methods(http.MethodDelete)
+	api.Handle("/announcements/{id}/comments/{cid}/hide", can(rbac.AnnouncementModerate, h.HideComment)).Methods(http.MethodPost)
+	api.Handle("/announcements/{id}/comments/{cid}/unhide", can(rbac.AnnouncementModerate, h.UnhideComment)).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/{id}/reactions/{emoji}", h.SetReaction).Methods(http.MethodPut, http.MethodDelete)
+	api.Handle("/announcements/{id}/attachments", can(rbac.AnnouncementManage, h.UploadAttachments)).Methods(http.MethodPost)
+	api.HandleFunc("/announcements/{id}/attachments/{att}", h.DownloadAttachment).Methods(http.MethodGet, http.MethodHead)
+	api.HandleFunc("/announcements/{id}/attachments/{att}/thumbnail", h.DownloadAttachment).Methods(http.MethodGet, http.MethodHead)
+	api.Handle("/announcements/{id}/attachments/{att}", can(rbac.AnnouncementManage, h.DeleteAttachment)).Methods(http.MethodDelete)
 
-	return r
-}
+	api.HandleFunc("/allowlist/delegations", h.ListDelegations).Methods(http.MethodGet)
+	api.HandleFunc("/allowlist/delegations", h.CreateDelegation).Methods(http.MethodPost)
+	api.HandleFunc("/allowlist/delegations/{tc}", h.RevokeDelegation).Methods(http.MethodDelete)
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Requested-With")
+	api.HandleFunc("/device/register", h.RegisterDeviceTokenHandler).Methods(http.MethodPost)
+	api.HandleFunc("/device/unregister", h.UnregisterDeviceTokenHandler).Methods(http.MethodPost)
 
-		if strings.EqualFold(r.Method, http.MethodOptions) {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+	enibra := api.PathPrefix("/enibra").Subrouter()
+	enibra.Use(limits.Enibra)
+	enibra.HandleFunc("/personeller", h.EnibraPersonelListesiProxy).Methods(http.MethodGet)
+	enibra.HandleFunc("/detay", h.EnibraPersonelDetay).Methods(http.MethodGet)
+	enibra.HandleFunc("/personel", h.EnibraPersonelByTC).Methods(http.MethodGet)
+	enibra.HandleFunc("/vardiya-uyarilari", h.EnibraVardiyaUyarilari).Methods(http.MethodGet)
 
-		next.ServeHTTP(w, r)
-	})
+	admin := r.PathPrefix(adminPrefix).Subrouter()
+	if cfg.TLS.Enabled && cfg.TLS.ClientCAFile != "" {
+		admin.Use(middlewares.RequireClientCert)
+	}
+	admin.Handle("/allowlist", can(rbac.AllowlistManage, h.GetAllowlist)).Methods(http.MethodGet)
+	admin.Handle("/allowlist", can(rbac.AllowlistManage, h.AddAllowlist)).Methods(http.MethodPost)
+	admin.Handle("/allowlist/{tc}", can(rbac.AllowlistManage, h.RemoveAllowlist)).Methods(http.MethodDelete)
+	admin.Handle("/allowlist/import", can(rbac.AllowlistManage, h.ImportAllowlist)).Methods(http.MethodPost)
+	admin.Handle("/allowlist/export", can(rbac.AllowlistManage, h.ExportAllowlist)).Methods(http.MethodGet)
+	admin.Handle("/lockouts", can(rbac.LockoutsManage, handlers.ListLockouts(tcLocks, ipLocks))).Methods(http.MethodGet)
+	admin.Handle("/lockouts/{scope}/{key}", can(rbac.LockoutsManage, handlers.ClearLockout(tcLocks, ipLocks))).Methods(http.MethodDelete)
+	admin.Handle("/audit", can(rbac.AuditView, h.ListAudit)).Methods(http.MethodGet)
+	admin.Handle("/audit/verify", can(rbac.AuditView, h.VerifyAudit)).Methods(http.MethodGet)
+	admin.Handle("/roles", can(rbac.RolesManage, handlers.ListRoles(policy))).Methods(http.MethodGet)
+	admin.Handle("/roles/{role}", can(rbac.RolesManage, h.UpdateRole(policy))).Methods(http.MethodPut)
+
+	r.NotFoundHandler = reqid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.NotFound, map[string]any{"path": r.URL.Path})
+	}))
+
+	cors = middlewares.NewCORS(r, cfg.CORS, adminPrefix)
+	return cors.Handler(r)
+}
+
+// Reload applies the runtime-reloadable parts of cfg to h and the router
+// built by NewRouter. Nothing is changed when an error is returned.
+func Reload(h *handlers.API, cfg *config.Config) error {
+	if err := h.Reconfigure(cfg); err != nil {
+		return err
+	}
+	cors.Update(cfg.CORS, adminPrefix)
+	return nil
 }
 
 type loggingResponseWriter struct {
@@ -62,12 +145,32 @@ func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach Flush, Hijack and the deadline
+// setters of the underlying writer (bkz. handlers/stream.go).
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter { return lrw.ResponseWriter }
+
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		lrw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(lrw, r)
 		duration := time.Since(start)
-		log.Printf("[INFO] %s %s %d %s", r.Method, r.URL.Path, lrw.status, duration.Round(time.Millisecond))
+		log.Printf("[INFO] %s %s %d %s rid=%s", r.Method, identity.Redact(r.URL.Path), lrw.status, duration.Round(time.Millisecond), reqid.From(r.Context()))
+
+		route := routeTemplate(r)
+		metrics.HTTPRequests.Inc(r.Method, route, strconv.Itoa(lrw.status))
+		metrics.HTTPDuration.Observe(duration.Seconds(), r.Method, route)
+		metrics.HTTPResponseSize.Observe(float64(lrw.size), r.Method, route)
 	})
 }
+
+// routeTemplate returns the mux path template so metrics don't explode on
+// path parameters like {tc}.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
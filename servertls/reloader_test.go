@@ -0,0 +1,225 @@
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hys-go-backend/middlewares"
+)
+
+// testCert is a generated certificate with its key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newCert(t *testing.T, cn string, parent *testCert, isCA bool, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signKey := tmpl, key
+	if parent != nil {
+		signer, signKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	b, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+}
+
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// writeFile writes b with mtime now+age, so consecutive writes get distinct
+// stamps even within the file system's timestamp resolution.
+func writeFile(t *testing.T, path string, b []byte, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mt := time.Now().Add(age)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePair(t *testing.T, dir string, c *testCert, age time.Duration) (string, string) {
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, c.certPEM(), age)
+	writeFile(t, keyFile, c.keyPEM(t), age)
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	c, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderSwapsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test ca", nil, true, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writePair(t, dir, newCert(t, "first", ca, false, x509.ExtKeyUsageServerAuth), -time.Minute)
+
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := servedCommonName(t, r); got != "first" {
+		t.Fatalf("served %q, want first", got)
+	}
+
+	if changed, err := r.reload(); changed || err != nil {
+		t.Fatalf("reload without change = %v, %v", changed, err)
+	}
+
+	writePair(t, dir, newCert(t, "second", ca, false, x509.ExtKeyUsageServerAuth), 0)
+	changed, err := r.reload()
+	if !changed || err != nil {
+		t.Fatalf("reload = %v, %v; want changed", changed, err)
+	}
+	if got := servedCommonName(t, r); got != "second" {
+		t.Fatalf("served %q after reload, want second", got)
+	}
+}
+
+func TestReloaderKeepsOldCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "test ca", nil, true, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := writePair(t, dir, newCert(t, "good", ca, false, x509.ExtKeyUsageServerAuth), -time.Minute)
+
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, certFile, []byte("-----BEGIN CERTIFICATE-----\nbroken\n-----END CERTIFICATE-----\n"), 0)
+	if _, err := r.reload(); err == nil {
+		t.Fatal("reload of a broken certificate succeeded")
+	}
+	if got := servedCommonName(t, r); got != "good" {
+		t.Fatalf("served %q after failed reload, want good", got)
+	}
+
+	// Key belongs to another certificate.
+	other := newCert(t, "other", ca, false, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, other.certPEM(), time.Minute)
+	if _, err := r.reload(); err == nil {
+		t.Fatal("reload of a mismatched key pair succeeded")
+	}
+	if got := servedCommonName(t, r); got != "good" {
+		t.Fatalf("served %q after mismatched reload, want good", got)
+	}
+
+	if _, err := NewReloader(certFile, keyFile, ""); err == nil {
+		t.Fatal("NewReloader accepted a mismatched key pair")
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newCert(t, "server ca", nil, true, x509.ExtKeyUsageServerAuth)
+	clientCA := newCert(t, "client ca", nil, true, x509.ExtKeyUsageClientAuth)
+	strangerCA := newCert(t, "stranger ca", nil, true, x509.ExtKeyUsageClientAuth)
+
+	certFile, keyFile := writePair(t, dir, newCert(t, "server", serverCA, false, x509.ExtKeyUsageServerAuth), 0)
+	caFile := filepath.Join(dir, "client-ca.crt")
+	writeFile(t, caFile, clientCA.certPEM(), 0)
+
+	r, err := NewReloader(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(middlewares.RequireClientCert(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})))
+	srv.TLS = r.ServerConfig("1.2")
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	// GetClientCertificate sends the certificate even when its CA is not in
+	// the server's list, so the server has to reject it itself.
+	get := func(cert *tls.Certificate) (int, error) {
+		cfg := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return cert, nil }
+		}
+		cli := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		defer cli.CloseIdleConnections()
+		resp, err := cli.Get(srv.URL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := get(nil); err != nil || code != http.StatusForbidden {
+		t.Errorf("without client cert: %d, %v; want 403", code, err)
+	}
+	good := newCert(t, "client", clientCA, false, x509.ExtKeyUsageClientAuth).tlsCert()
+	if code, err := get(&good); err != nil || code != http.StatusOK {
+		t.Errorf("with client cert: %d, %v; want 200", code, err)
+	}
+	stranger := newCert(t, "stranger", strangerCA, false, x509.ExtKeyUsageClientAuth).tlsCert()
+	if code, err := get(&stranger); err == nil {
+		t.Errorf("client cert from an unknown CA was accepted: %d", code)
+	}
+}
@@ -0,0 +1,179 @@
+// Package servertls builds the HTTPS server configuration: modern protocol
+// settings, certificates that are reloaded when the files change on disk,
+// optional client certificates and an HTTP->HTTPS redirect handler.
+package servertls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"hys-go-backend/metrics"
+)
+
+// Reloader keeps the server certificate (and optional client CA pool) in
+// sync with the files on disk. Files are polled, so it works the same on
+// bind mounts and Kubernetes secrets where inotify events are unreliable.
+type Reloader struct {
+	certFile, keyFile, caFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	stamp    string
+}
+
+// NewReloader loads the files once and fails if they are unusable.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate satisfies tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch polls the files every interval until ctx is done. A broken
+// certificate is logged and the previous one stays in use.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			start := time.Now()
+			changed, err := r.reload()
+			if changed || err != nil {
+				metrics.ObserveJob("tls_reload", start, err)
+			}
+			if err != nil {
+				log.Printf("[ERROR] tls reload: %v (keeping previous certificate)", err)
+			} else if changed {
+				log.Printf("[INFO] tls certificate reloaded from %s", r.certFile)
+			}
+		}
+	}
+}
+
+// reload reads the files when their size or mtime changed.
+func (r *Reloader) reload() (bool, error) {
+	stamp, err := fileStamp(r.certFile, r.keyFile, r.caFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	same := stamp == r.stamp
+	r.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return false, fmt.Errorf("client ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, errors.New("client ca: no certificates found in " + r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCA, r.stamp = &cert, pool, stamp
+	r.mu.Unlock()
+	return true, nil
+}
+
+func fileStamp(paths ...string) (string, error) {
+	var out string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		st, err := os.Stat(p)
+		if err != nil {
+			return "", err
+		}
+		out += fmt.Sprintf("%s:%d:%d;", p, st.Size(), st.ModTime().UnixNano())
+	}
+	return out, nil
+}
+
+// ServerConfig returns the tls.Config for the HTTPS listener. When a client
+// CA is configured, certificates are requested and verified if presented;
+// RequireClientCert decides per route whether one is mandatory.
+func (r *Reloader) ServerConfig(minVersion string) *tls.Config {
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519, tls.CurveP256,
+		},
+		// Yalnızca TLS 1.2 için geçerli; 1.3 suite'leri Go tarafından sabit.
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if minVersion == "1.3" {
+		base.MinVersion = tls.VersionTLS13
+	}
+	if r.caFile == "" {
+		return base
+	}
+
+	// CA havuzu da yenilenebildiği için her el sıkışmada güncel olanı ver.
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		pool := r.clientCA
+		r.mu.RUnlock()
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		return cfg, nil
+	}
+	return base
+}
+
+// RedirectHandler sends every request to the same path on the HTTPS address.
+func RedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,53 @@
+// Package storage holds binary objects (announcement attachments and their
+// thumbnails) on local disk or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"hys-go-backend/config"
+)
+
+// ErrNotFound is returned by Open when the key does not exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// BlobStore stores opaque objects under slash-separated keys.
+type BlobStore interface {
+	// Put stores size bytes from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object and its size. The caller closes the reader.
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New builds the store selected by cfg.Backend.
+func New(cfg config.BlobConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocal(cfg.Dir)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:    cfg.S3Endpoint,
+			Region:      cfg.S3Region,
+			Bucket:      cfg.S3Bucket,
+			AccessKey:   cfg.S3AccessKey,
+			SecretKey:   cfg.S3SecretKey,
+			VirtualHost: cfg.S3VirtualHost,
+		})
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q", cfg.Backend)
+}
+
+// checkKey rejects keys that could escape the store root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.Contains(key, "..") || strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-central-1"
+	testBucket    = "hys-test"
+)
+
+// fakeS3 is an in-memory stand-in for an S3 bucket. It verifies the SigV4
+// Authorization header of every request on its own, without the client's
+// signing code.
+type fakeS3 struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	reqs    []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if msg := f.verify(r); msg != "" {
+		f.t.Errorf("%s %s: %s", r.Method, r.URL.EscapedPath(), msg)
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reqs = append(f.reqs, r.Method+" "+r.URL.EscapedPath())
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(b)) {
+			f.t.Errorf("PUT %s: Content-Length %d, body %d bytes", key, r.ContentLength, len(b))
+		}
+		f.objects[key], f.types[key] = b, r.Header.Get("Content-Type")
+	case http.MethodGet:
+		b, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the signature from the request as S3 would and returns
+// a description of the first mismatch.
+func (f *fakeS3) verify(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	rest, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "missing AWS4-HMAC-SHA256 Authorization: " + auth
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(rest, ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != 16 {
+		return "bad X-Amz-Date " + amzDate
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if want := testAccessKey + "/" + scope; fields["Credential"] != want {
+		return "Credential " + fields["Credential"] + ", want " + want
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		return "X-Amz-Content-Sha256 " + r.Header.Get("X-Amz-Content-Sha256")
+	}
+
+	var headers strings.Builder
+	for _, h := range strings.Split(fields["SignedHeaders"], ";") {
+		v := r.Header.Get(h)
+		if h == "host" {
+			v = r.Host
+		}
+		headers.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	canonical := strings.Join([]string{
+		r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers.String(), fields["SignedHeaders"], "UNSIGNED-PAYLOAD",
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{amzDate[:8], testRegion, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, toSign)); fields["Signature"] != want {
+		return "Signature " + fields["Signature"] + ", want " + want
+	}
+	return ""
+}
+
+func newTestS3(t *testing.T, endpoint string) *S3 {
+	t.Helper()
+	s, err := NewS3(S3Options{
+		Endpoint: endpoint, Region: testRegion, Bucket: testBucket,
+		AccessKey: testAccessKey, SecretKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC) }
+	return s
+}
+
+func TestS3PutOpenDelete(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	s := newTestS3(t, srv.URL)
+	ctx := context.Background()
+	key := "announcements/20261018.1/a b+ç.pdf"
+	body := "%PDF-1.4 test"
+
+	if err := s.Put(ctx, key, strings.NewReader(body), int64(len(body)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.types[key]; got != "application/pdf" {
+		t.Errorf("stored content type %q", got)
+	}
+
+	rc, size, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != body || size != int64(len(body)) {
+		t.Errorf("Open = %q (%d bytes), want %q", b, size, body)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := s.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+
+	want := "PUT /hys-test/announcements/20261018.1/a%20b%2B%C3%A7.pdf"
+	if len(fake.reqs) == 0 || fake.reqs[0] != want {
+		t.Errorf("first request %v, want %q", fake.reqs, want)
+	}
+}
+
+func TestS3Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	s := newTestS3(t, srv.URL)
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "k", strings.NewReader("x"), 1, ""); err == nil || !strings.Contains(err.Error(), "status=403") {
+		t.Errorf("Put = %v, want status=403 error", err)
+	}
+	if _, _, err := s.Open(ctx, "k"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open = %v, want non-NotFound error", err)
+	}
+	if err := s.Delete(ctx, "k"); err == nil {
+		t.Error("Delete on 403 returned nil")
+	}
+	if err := s.Put(ctx, "../escape", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("Put accepted a traversal key")
+	}
+}
+
+func TestNewS3Options(t *testing.T) {
+	if _, err := NewS3(S3Options{Endpoint: "not a url", Bucket: "b"}); err == nil {
+		t.Error("invalid endpoint accepted")
+	}
+	if _, err := NewS3(S3Options{Endpoint: "http://127.0.0.1:9000"}); err == nil {
+		t.Error("missing bucket accepted")
+	}
+}
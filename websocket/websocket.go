@@ -0,0 +1,270 @@
+// Package websocket implements the server side of RFC 6455 as far as the
+// live stream needs it: the upgrade handshake, unfragmented text frames from
+// the server, and ping/pong/close handling for frames from the client.
+// Uzantılar (permessage-deflate vb.) ve alt protokoller desteklenmez.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes (RFC 6455 5.2).
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// Close codes used by the server (RFC 6455 7.4.1).
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+)
+
+// MaxMessageBytes limits messages read from the client. İstemci yalnızca
+// kısa kontrol mesajları gönderir.
+const MaxMessageBytes = 64 << 10
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrNotWebSocket = errors.New("websocket: not a websocket handshake")
+	ErrBadVersion   = errors.New("websocket: unsupported version")
+	ErrTooBig       = errors.New("websocket: message too big")
+	ErrProtocol     = errors.New("websocket: protocol error")
+)
+
+// IsUpgrade reports whether r asks for a websocket upgrade.
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Conn is an upgraded connection. Writes are safe for concurrent use;
+// ReadMessage must be called from one goroutine.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	idle time.Duration
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// Upgrade completes the handshake and takes over the connection. On error
+// nothing has been written, so the caller can still send an HTTP error.
+// header is added to the 101 response.
+func Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, ErrBadVersion
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, ErrNotWebSocket
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	// Sunucunun ReadTimeout/WriteTimeout süreleri bağlantıda kalmasın;
+	// süreleri artık çağıran yönetir.
+	_ = conn.SetDeadline(time.Time{})
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	for k, vals := range header {
+		for _, v := range vals {
+			resp.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	resp.WriteString("\r\n")
+	if _, err := conn.Write([]byte(resp.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// WriteText sends one text message. deadline bounds the write; zero means
+// no deadline.
+func (c *Conn) WriteText(p []byte, deadline time.Time) error {
+	return c.writeFrame(OpText, p, deadline)
+}
+
+// Ping sends a ping; browsers answer with a pong, which ReadMessage
+// consumes.
+func (c *Conn) Ping(deadline time.Time) error {
+	return c.writeFrame(OpPing, nil, deadline)
+}
+
+// Close sends a close frame with code and reason and closes the connection.
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	_ = c.writeFrame(OpClose, payload, time.Now().Add(time.Second))
+	c.wmu.Lock()
+	c.closed = true
+	c.wmu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(op byte, p []byte, deadline time.Time) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	hdr := make([]byte, 2, 10)
+	hdr[0] = 0x80 | op // FIN; sunucu çerçeveleri maskelenmez
+	switch n := len(p); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xFFFF:
+		hdr[1] = 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+	_ = c.conn.SetWriteDeadline(deadline)
+	if _, err := c.conn.Write(append(hdr, p...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetIdleTimeout bounds the wait for each frame from the client, pongs
+// included, so a client that answers pings stays connected.
+func (c *Conn) SetIdleTimeout(d time.Duration) { c.idle = d }
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// and pongs skipped; a close frame is answered and reported as io.EOF.
+func (c *Conn) ReadMessage() (op byte, msg []byte, err error) {
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch frameOp {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload, time.Now().Add(5*time.Second)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			_ = c.Close(CloseNormal, "")
+			return 0, nil, io.EOF
+		case OpText, OpBinary:
+			if op != 0 {
+				return 0, nil, ErrProtocol // önceki mesaj bitmeden yeni mesaj
+			}
+			op = frameOp
+		case OpContinuation:
+			if op == 0 {
+				return 0, nil, ErrProtocol
+			}
+		default:
+			return 0, nil, ErrProtocol
+		}
+		if len(msg)+len(payload) > MaxMessageBytes {
+			return 0, nil, ErrTooBig
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return op, msg, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	if c.idle > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.idle))
+	}
+	var hdr [2]byte
+	if _, err = io.ReadFull(c.br, hdr[:]); err != nil {
+		return
+	}
+	fin, op = hdr[0]&0x80 != 0, hdr[0]&0x0F
+	if hdr[0]&0x70 != 0 || hdr[1]&0x80 == 0 {
+		// RSV bitleri uzantı olmadan sıfır olmalı; istemci çerçeveleri maskeli olmalı.
+		return false, 0, nil, ErrProtocol
+	}
+
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= OpClose && (n > 125 || !fin) {
+		return false, 0, nil, ErrProtocol
+	}
+	if n > MaxMessageBytes {
+		return false, 0, nil, ErrTooBig
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type frame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+// pipe returns a server Conn and the client end of an in-memory
+// connection. Frames the server writes are collected on the channel.
+func pipe(t *testing.T) (*Conn, net.Conn, <-chan frame) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() { server.Close(); client.Close() })
+
+	frames := make(chan frame, 16)
+	go func() {
+		defer close(frames)
+		br := bufio.NewReader(client)
+		for {
+			f, err := readServerFrame(br)
+			if err != nil {
+				return
+			}
+			frames <- f
+		}
+	}()
+	return &Conn{conn: server, br: bufio.NewReader(server)}, client, frames
+}
+
+// clientFrame encodes a frame as a client sends it; masked=false breaks
+// RFC 6455 on purpose.
+func clientFrame(fin bool, op byte, payload []byte, masked bool) []byte {
+	b := []byte{op, 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] = byte(n)
+	case n <= 0xFFFF:
+		b[1] = 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] = 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if !masked {
+		return append(b, payload...)
+	}
+	b[1] |= 0x80
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask[:]...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func readServerFrame(r io.Reader) (frame, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return frame{}, err
+	}
+	if hdr[1]&0x80 != 0 {
+		return frame{}, errors.New("server frame is masked")
+	}
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return frame{}, err
+	}
+	return frame{fin: hdr[0]&0x80 != 0, op: hdr[0] & 0x0F, payload: payload}, nil
+}
+
+// send writes the frames from a goroutine: net.Pipe blocks until the
+// server reads, and the server may stop reading at an error.
+func send(client net.Conn, frames ...[]byte) {
+	go func() {
+		for _, f := range frames {
+			if _, err := client.Write(f); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func nextFrame(t *testing.T, frames <-chan frame) frame {
+	t.Helper()
+	select {
+	case f, ok := <-frames:
+		if !ok {
+			t.Fatal("connection closed before a frame arrived")
+		}
+		return f
+	case <-time.After(2 * time.Second):
+		t.Fatal("no frame from the server")
+	}
+	return frame{}
+}
+
+func TestReadMessage(t *testing.T) {
+	big := bytes.Repeat([]byte("a"), MaxMessageBytes/2+1)
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		wantOp  byte
+		wantMsg string
+		wantErr error
+	}{
+		{"text", [][]byte{clientFrame(true, OpText, []byte("merhaba"), true)}, OpText, "merhaba", nil},
+		{"binary", [][]byte{clientFrame(true, OpBinary, []byte{1, 2}, true)}, OpBinary, "\x01\x02", nil},
+		{"fragmented text", [][]byte{
+			clientFrame(false, OpText, []byte("mer"), true),
+			clientFrame(false, OpContinuation, []byte("ha"), true),
+			clientFrame(true, OpContinuation, []byte("ba"), true),
+		}, OpText, "merhaba", nil},
+		{"pong between fragments", [][]byte{
+			clientFrame(false, OpText, []byte("a"), true),
+			clientFrame(true, OpPong, nil, true),
+			clientFrame(true, OpContinuation, []byte("b"), true),
+		}, OpText, "ab", nil},
+		{"16-bit length", [][]byte{clientFrame(true, OpText, bytes.Repeat([]byte("x"), 300), true)}, OpText, string(bytes.Repeat([]byte("x"), 300)), nil},
+		{"unmasked client frame", [][]byte{clientFrame(true, OpText, []byte("x"), false)}, 0, "", ErrProtocol},
+		{"reserved bit set", [][]byte{append([]byte{0xC1}, clientFrame(true, OpText, []byte("x"), true)[1:]...)}, 0, "", ErrProtocol},
+		{"continuation without start", [][]byte{clientFrame(true, OpContinuation, []byte("x"), true)}, 0, "", ErrProtocol},
+		{"new message before fin", [][]byte{
+			clientFrame(false, OpText, []byte("a"), true),
+			clientFrame(true, OpText, []byte("b"), true),
+		}, 0, "", ErrProtocol},
+		{"fragmented ping", [][]byte{clientFrame(false, OpPing, nil, true)}, 0, "", ErrProtocol},
+		{"control frame over 125 bytes", [][]byte{clientFrame(true, OpPing, bytes.Repeat([]byte("p"), 126), true)}, 0, "", ErrProtocol},
+		{"unknown opcode", [][]byte{clientFrame(true, 0x3, nil, true)}, 0, "", ErrProtocol},
+		{"oversized frame", [][]byte{clientFrame(true, OpText, bytes.Repeat([]byte("a"), MaxMessageBytes+1), true)}, 0, "", ErrTooBig},
+		{"oversized fragmented message", [][]byte{
+			clientFrame(false, OpText, big, true),
+			clientFrame(true, OpContinuation, big, true),
+		}, 0, "", ErrTooBig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, client, _ := pipe(t)
+			send(client, tt.frames...)
+
+			op, msg, err := c.ReadMessage()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if op != tt.wantOp || string(msg) != tt.wantMsg {
+				t.Errorf("got op %d %q, want op %d %q", op, msg, tt.wantOp, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestPingIsAnswered(t *testing.T) {
+	c, client, frames := pipe(t)
+	send(client,
+		clientFrame(true, OpPing, []byte("hb"), true),
+		clientFrame(true, OpText, []byte("x"), true),
+	)
+
+	if _, msg, err := c.ReadMessage(); err != nil || string(msg) != "x" {
+		t.Fatalf("ReadMessage = %q, %v", msg, err)
+	}
+	f := nextFrame(t, frames)
+	if f.op != OpPong || !f.fin || string(f.payload) != "hb" {
+		t.Errorf("got %+v, want pong echoing the ping payload", f)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	c, client, frames := pipe(t)
+	payload := binary.BigEndian.AppendUint16(nil, CloseNormal)
+	send(client, clientFrame(true, OpClose, payload, true))
+
+	if _, _, err := c.ReadMessage(); err != io.EOF {
+		t.Fatalf("ReadMessage err = %v, want io.EOF", err)
+	}
+	f := nextFrame(t, frames)
+	if f.op != OpClose || binary.BigEndian.Uint16(f.payload) != CloseNormal {
+		t.Errorf("got %+v, want close %d", f, CloseNormal)
+	}
+	if err := c.WriteText([]byte("late"), time.Time{}); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("WriteText after close = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	c, _, frames := pipe(t)
+	go c.Close(CloseGoingAway, "shutdown")
+
+	f := nextFrame(t, frames)
+	if f.op != OpClose || binary.BigEndian.Uint16(f.payload) != CloseGoingAway || string(f.payload[2:]) != "shutdown" {
+		t.Errorf("got %+v, want close %d \"shutdown\"", f, CloseGoingAway)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		c, _, frames := pipe(t)
+		msg := bytes.Repeat([]byte("z"), n)
+		go c.WriteText(msg, time.Time{})
+
+		f := nextFrame(t, frames)
+		if f.op != OpText || !f.fin || !bytes.Equal(f.payload, msg) {
+			t.Errorf("len %d: got op %d fin %v len %d", n, f.op, f.fin, len(f.payload))
+		}
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	c, _, _ := pipe(t)
+	c.SetIdleTimeout(20 * time.Millisecond)
+	_, _, err := c.ReadMessage()
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 1.3'teki örnek.
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("acceptKey = %s", got)
+	}
+}